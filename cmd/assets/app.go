@@ -12,7 +12,9 @@ import (
 	"path/filepath"
 
 	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/jonathonwebb/tilde/internal/bundle"
 	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/view"
 )
 
 type metafile struct {
@@ -41,25 +43,13 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 		return err
 	}
 
-	res := esbuild.Build(esbuild.BuildOptions{
-		EntryPoints: []string{
-			path.Join(cfg.AssetsDir, "entrypoints/**/*.ts"),
-			path.Join(cfg.AssetsDir, "entrypoints/**/*.tsx"),
-			path.Join(cfg.AssetsDir, "entrypoints/**/*.css"),
-		},
-		AssetNames:  "[name]-[hash]",
-		ChunkNames:  "[name]-[hash]",
-		EntryNames:  "[name]-[hash]",
-		Sourcemap:   esbuild.SourceMapInline,
-		Outdir:      path.Join(cfg.StaticDir, "build"),
-		Bundle:      true,
-		Metafile:    true,
-		Write:       false,
-		Format:      esbuild.FormatESModule,
-		JSX:         esbuild.JSXTransform,
-		JSXFactory:  "h",
-		JSXFragment: "Fragment",
-	})
+	opts := bundle.Options(cfg.AssetsDir, cfg.StaticDir)
+	opts.AssetNames = "[name]-[hash]"
+	opts.ChunkNames = "[name]-[hash]"
+	opts.EntryNames = "[name]-[hash]"
+	opts.Metafile = true
+	opts.Write = false
+	res := esbuild.Build(opts)
 	for _, err := range res.Errors {
 		log.Error("build error", "msg", err)
 	}
@@ -101,7 +91,7 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 					a.Path = filepath.Join("/public", rel2)
 
 					h.Write(o.Contents)
-					a.SRI = base64.StdEncoding.EncodeToString(h.Sum(nil))
+					a.SRI = "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
 					h.Reset()
 
 					rel, err := filepath.Rel(filepath.Join(cfg.AssetsDir, "entrypoints"), entry.EntryPoint)
@@ -123,7 +113,7 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 	if err := os.MkdirAll(path.Join(cfg.StaticDir, "build"), 0755); err != nil {
 		return err
 	}
-	if err := os.WriteFile(path.Join(cfg.StaticDir, "build/meta.json"), metaJson, 0644); err != nil {
+	if err := os.WriteFile(path.Join(cfg.StaticDir, view.ManifestPath), metaJson, 0644); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+// run opens the database and calls fn with the audit store, logging any error.
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, fn func(*slog.Logger, *store.AuditStore) error) (err error) {
+	log := cfg.NewLogger(e.Stderr, "audit")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return err
+	}
+
+	return fn(log, store.NewAuditStore(db))
+}
+
+func runList(ctx context.Context, e *cli.Env, cfg *core.Config, f store.AuditFilter) error {
+	return run(ctx, e, cfg, func(log *slog.Logger, audit *store.AuditStore) error {
+		events, err := audit.List(ctx, f)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]any, len(events))
+		for i, ev := range events {
+			rows[i] = []any{ev.Id, ev.CreatedAt, ev.Actor, ev.Action, ev.EntityType, ev.EntityId, nullable(ev.Before), nullable(ev.After), nullable(ev.RequestId)}
+		}
+		return cli.WriteRows(e.Stdout, cfg.Output, []string{"id", "time", "actor", "action", "entity_type", "entity_id", "before", "after", "request_id"}, rows)
+	})
+}
+
+func runPrune(ctx context.Context, e *cli.Env, cfg *core.Config) error {
+	return run(ctx, e, cfg, func(log *slog.Logger, audit *store.AuditStore) error {
+		before := time.Now().Add(-cfg.AuditRetain)
+		n, err := audit.Prune(ctx, before)
+		if err != nil {
+			return err
+		}
+		log.Info("pruned audit events", "before", before.UTC(), "n", n)
+		return nil
+	})
+}
+
+func nullable(v sql.Null[string]) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.V
+}
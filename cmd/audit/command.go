@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	listUsage = "usage: tilde [root flags] audit list [-h] [flags]"
+	listHelp  = `usage: tilde [root flags] audit list [-h] [flags]
+
+list audit events, newest first.
+
+flags:
+  -actor=<actor>         only events by <actor>, e.g. cli:root or user:1
+  -entity=<type[:id]>    only events on an entity type, e.g. org or user:3
+  -format=table          output format (table|json|csv)
+  -limit=100             maximum number of events to list
+  -since=<time>          only events at or after an RFC 3339 time
+  -until=<time>          only events before an RFC 3339 time
+  -h, -help              show this help and exit`
+
+	pruneUsage = "usage: tilde [root flags] audit prune [-h] [flags]"
+	pruneHelp  = `usage: tilde [root flags] audit prune [-h] [flags]
+
+delete audit events older than the retention window.
+
+flags:
+  -retain=2160h   audit retention window ($TLD_AUDIT_RETAIN)
+  -h, -help       show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "audit",
+	Usage: "usage: tilde [root flags] audit <command>",
+	Help: `usage: tilde [root flags] audit <command>
+
+inspect the audit log of data changes.
+
+commands:
+  list        list audit events
+  prune       delete expired audit events
+
+flags:
+  -h, -help   show this help and exit`,
+	Commands: []*cli.Command{
+		&listCmd,
+		&pruneCmd,
+	},
+}
+
+var listCmd = cli.Command{
+	Name:  "list",
+	Usage: listUsage,
+	Help:  listHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.AuditActor, "actor", "", "")
+		fs.StringVar(&cfg.AuditEntity, "entity", "", "")
+		fs.TextVar(&cfg.Output, "format", &cli.TableOutput, "")
+		fs.IntVar(&cfg.ListLimit, "limit", store.DefaultLimit, "")
+		fs.TextVar(&cfg.AuditSince, "since", time.Time{}, "")
+		fs.TextVar(&cfg.AuditUntil, "until", time.Time{}, "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(listUsage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		f := store.AuditFilter{
+			Actor: cfg.AuditActor,
+			Since: cfg.AuditSince,
+			Until: cfg.AuditUntil,
+			Limit: cfg.ListLimit,
+		}
+		if cfg.AuditEntity != "" {
+			kind, id, ok := strings.Cut(cfg.AuditEntity, ":")
+			f.EntityType = kind
+			if ok {
+				n, err := strconv.ParseInt(id, 10, 64)
+				if err != nil {
+					e.PrintUsageErr(listUsage, "invalid value %q for flag -entity: bad id", cfg.AuditEntity)
+					return cli.ExitUsageError
+				}
+				f.EntityId = n
+			}
+		}
+
+		if err := runList(ctx, e, cfg, f); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var pruneCmd = cli.Command{
+	Name:  "prune",
+	Usage: pruneUsage,
+	Help:  pruneHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.DurationVar(&cfg.AuditRetain, "retain", store.DefaultAuditRetain, "")
+	},
+	Vars: map[string]string{
+		"retain": "TLD_AUDIT_RETAIN",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(pruneUsage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if err := runPrune(ctx, e, cfg); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -0,0 +1,94 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jonathonwebb/tilde/internal/authz"
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, user, action, resource string) (_ bool, err error) {
+	log := cfg.NewLogger(e.Stderr, "authz")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return false, err
+	}
+
+	users, orgs := store.NewUserStore(db), store.NewOrgStore(db)
+
+	var sub authz.Subject
+	if user != "-" {
+		u, err := users.GetByName(ctx, user)
+		if err != nil {
+			return false, err
+		}
+		sub.UserId = u.Id
+	}
+
+	var res authz.Resource
+	kind, name, ok := strings.Cut(resource, ":")
+	switch {
+	case kind == "user" && ok:
+		u, err := users.GetByName(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		res = authz.Resource{Kind: kind, Id: u.Id, OwnerId: u.Id}
+	case kind == "org" && ok:
+		o, err := orgs.GetByName(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		res = authz.Resource{Kind: kind, Id: o.Id, OrgId: o.Id}
+	case ok:
+		return false, fmt.Errorf("unknown resource %s, expected user:<username>, org:<name> or <kind>", resource)
+	default:
+		res.Kind = kind
+	}
+	if cfg.AuthzOrg != "" {
+		o, err := orgs.GetByName(ctx, cfg.AuthzOrg)
+		if err != nil {
+			return false, err
+		}
+		res.OrgId = o.Id
+	}
+	if cfg.AuthzOwner != "" {
+		u, err := users.GetByName(ctx, cfg.AuthzOwner)
+		if err != nil {
+			return false, err
+		}
+		res.OwnerId = u.Id
+	}
+
+	a := &authz.Authorizer{Policy: authz.DefaultPolicy, Roles: orgs}
+	d, err := a.Check(ctx, sub, authz.Action(action), res)
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintln(e.Stdout, d); err != nil {
+		return false, err
+	}
+	return d.Allowed, nil
+}
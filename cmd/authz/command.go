@@ -0,0 +1,65 @@
+package authz
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	checkUsage = "usage: tilde [root flags] authz check [-h] [flags] <user> <action> <resource>"
+	checkHelp  = `usage: tilde [root flags] authz check [-h] [flags] <user> <action> <resource>
+
+decide whether <user> may perform <action> on <resource> and print the reason.
+<user> is a username, or - for an anonymous user. <resource> is one of
+user:<username>, org:<name>, or a resource kind described by the flags. exits
+with status 1 when the action is denied.
+
+flags:
+  -org=<name>      org the resource belongs to
+  -owner=<user>    user who owns the resource
+  -h, -help        show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "authz",
+	Usage: "usage: tilde [root flags] authz <command>",
+	Help: `usage: tilde [root flags] authz <command>
+
+inspect authorization decisions.
+
+commands:
+  check       explain a permission check
+
+flags:
+  -h, -help   show this help and exit`,
+	Commands: []*cli.Command{
+		&checkCmd,
+	},
+}
+
+var checkCmd = cli.Command{
+	Name:  "check",
+	Usage: checkUsage,
+	Help:  checkHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.AuthzOrg, "org", "", "")
+		fs.StringVar(&cfg.AuthzOwner, "owner", "", "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 3 {
+			e.PrintUsageErr(checkUsage, "expected <user>, <action> and <resource> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		allowed, err := run(ctx, e, cfg, e.Args[0], e.Args[1], e.Args[2])
+		if err != nil || !allowed {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -0,0 +1,44 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/backup"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
+	log := cfg.NewLogger(w, "backup")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m, err := backup.Create(ctx, db, cfg.BackupDir, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Info("created backup", "file", m.File, "schema", m.SchemaVersion, "size", m.Size)
+
+	removed, err := backup.Prune(cfg.BackupDir, cfg.BackupKeep)
+	if err != nil {
+		return err
+	}
+	for _, p := range removed {
+		log.Info("removed backup", "manifest", p)
+	}
+	return nil
+}
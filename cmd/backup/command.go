@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	usage = "usage: tilde [root flags] backup [-h] [flags]"
+	help  = `usage: tilde [root flags] backup [-h] [flags]
+
+write a compressed online backup of the database and its manifest, then
+remove all but the newest -keep backups. safe to run while serving.
+
+flags:
+  -dir=backups   backup dir ($TLD_BACKUP_DIR)
+  -keep=7        number of backups to retain ($TLD_BACKUP_KEEP)
+  -h, -help      show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "backup",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.BackupDir, "dir", "backups", "")
+		fs.IntVar(&cfg.BackupKeep, "keep", 7, "")
+	},
+	Vars: map[string]string{
+		"dir":  "TLD_BACKUP_DIR",
+		"keep": "TLD_BACKUP_KEEP",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if cfg.BackupKeep < 1 {
+			e.PrintUsageErr(usage, "expected -keep >= 1, but got %d", cfg.BackupKeep)
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e.Stderr, cfg); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
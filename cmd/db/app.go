@@ -0,0 +1,72 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"golang.org/x/term"
+)
+
+// maxLine bounds the lines read from input that isn't a terminal, which can
+// hold long pasted statements.
+const maxLine = 64 << 20
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config) (err error) {
+	log := cfg.NewLogger(e.Stderr, "db")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	c := &console{pool: db.Reader(), out: e.Stdout, mode: cfg.Output}
+	switch {
+	case cfg.DbConsoleWrite:
+		c.pool = db.Writer()
+	case db.InMemory():
+		// reads share the writer's only connection, so it's made read-only
+		if _, err := db.ExecContext(ctx, "PRAGMA query_only = true"); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DbConsoleCommand != "" {
+		return c.run(ctx, cfg.DbConsoleCommand)
+	}
+
+	f, ok := e.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		sc := bufio.NewScanner(e.Stdin)
+		sc.Buffer(nil, maxLine)
+		return c.repl(ctx, scanReader{sc})
+	}
+
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, term.Restore(int(f.Fd()), state))
+	}()
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{f, e.Stdout}, "")
+	c.out = t
+	return c.repl(ctx, t)
+}
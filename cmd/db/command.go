@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	usage = "usage: tilde [root flags] db [-h] [flags]"
+	help  = `usage: tilde [root flags] db [-h] [flags]
+
+open an interactive sql console on the configured database. the console is
+read-only unless -write is set.
+
+flags:
+  -c=<sql>       run <sql> and exit
+  -mode=table    output mode (table|json|csv)
+  -write         allow statements that modify the database
+  -h, -help      show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "db",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.DbConsoleCommand, "c", "", "")
+		fs.TextVar(&cfg.Output, "mode", &cli.TableOutput, "")
+		fs.BoolVar(&cfg.DbConsoleWrite, "write", false, "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e, cfg); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -0,0 +1,158 @@
+package db_test
+
+import (
+	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/cmd/db"
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func TestDbCommand(t *testing.T) {
+	t.Run("read only by default", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, "-c", "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitFailure {
+			t.Errorf("want exit status = %v, but got %v", cli.ExitFailure, gotCode)
+		}
+		if !strings.Contains(errBuf.String(), "readonly") {
+			t.Errorf("want readonly error, but got %q", errBuf.String())
+		}
+		if outBuf.Len() != 0 {
+			t.Errorf("want no output, but got %q", outBuf.String())
+		}
+	})
+
+	t.Run("read only in memory", func(t *testing.T) {
+		e, cfg, errBuf, _ := setUp(t, "-c", "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+		cfg.DbConnString = ":memory:"
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitFailure {
+			t.Errorf("want exit status = %v, but got %v", cli.ExitFailure, gotCode)
+		}
+		if !strings.Contains(errBuf.String(), "readonly") {
+			t.Errorf("want readonly error, but got %q", errBuf.String())
+		}
+	})
+
+	t.Run("write and query", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, "-write", "-mode=csv", "-c", `
+CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT); -- comment; not a statement
+INSERT INTO t (name) VALUES ('a;b'), (NULL);
+SELECT * FROM t`)
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, gotCode, errBuf)
+		}
+
+		want := "id,name\n1,a;b\n2,\n"
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("console", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, "-write")
+		e.Stdin = strings.NewReader(`CREATE TABLE t (
+  id INTEGER PRIMARY KEY
+);
+.tables
+.mode json
+SELECT id
+  FROM t;
+.bogus
+.quit
+SELECT 1;
+`)
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, gotCode, errBuf)
+		}
+
+		want := `t
+[]
+error: unknown command .bogus, see .help
+`
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("long line", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, "-mode=csv")
+		long := strings.Repeat("x", 1<<20)
+		e.Stdin = strings.NewReader("SELECT length('" + long + "') AS n;\n")
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, gotCode, errBuf)
+		}
+
+		want := "n\n1048576\n"
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("triggers", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, "-write", "-mode=csv")
+		e.Stdin = strings.NewReader(`CREATE TABLE t (id INTEGER PRIMARY KEY, n INTEGER);
+CREATE TABLE log (msg TEXT);
+CREATE TEMP TRIGGER t_insert AFTER INSERT ON t BEGIN
+  INSERT INTO log VALUES (CASE WHEN new.n > 1 THEN 'big' ELSE 'small' END);
+  /* a comment; with a semicolon */
+  INSERT INTO log VALUES ('end;');
+END;
+BEGIN;
+INSERT INTO t (n) VALUES (1), (2);
+END;
+SELECT msg FROM log;
+`)
+
+		cmd := db.Cmd
+		gotCode := cmd.Execute(t.Context(), e, cfg)
+		if gotCode != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, gotCode, errBuf)
+		}
+
+		want := "msg\nsmall\nend;\nbig\nend;\n"
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func setUp(t testing.TB, args ...string) (*cli.Env, *core.Config, *strings.Builder, *strings.Builder) {
+	t.Helper()
+
+	var errBuf, outBuf strings.Builder
+
+	return &cli.Env{
+			Log:    log.New(&errBuf, "", 0),
+			Stderr: &errBuf,
+			Stdout: &outBuf,
+			Stdin:  strings.NewReader(""),
+			Args:   append([]string{"db"}, args...),
+		}, &core.Config{
+			Env:          "test",
+			Level:        slog.LevelError,
+			Format:       core.JSONFormat,
+			DbConnString: filepath.Join(t.TempDir(), "test.db"),
+		},
+		&errBuf,
+		&outBuf
+}
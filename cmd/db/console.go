@@ -0,0 +1,272 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+)
+
+const consoleHelp = `.help               show this help
+.mode [table|json|csv]
+                    show or set the output mode
+.quit, .exit        exit the console
+.schema [table]     show CREATE statements
+.tables             list tables and views
+
+statements end with ";" and may span lines.`
+
+var errQuit = errors.New("quit")
+
+type lineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(string)
+}
+
+// scanReader reads lines without editing, for input that isn't a terminal.
+type scanReader struct {
+	*bufio.Scanner
+}
+
+func (r scanReader) ReadLine() (string, error) {
+	if !r.Scan() {
+		if err := r.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.Text(), nil
+}
+
+func (r scanReader) SetPrompt(string) {}
+
+type console struct {
+	pool *sql.DB
+	out  io.Writer
+	mode cli.OutputFormat
+}
+
+// repl reads statements from in until EOF or .quit. Errors from individual
+// statements are reported without ending the session.
+func (c *console) repl(ctx context.Context, in lineReader) error {
+	var pending strings.Builder
+	for {
+		if pending.Len() == 0 {
+			in.SetPrompt("tilde> ")
+		} else {
+			in.SetPrompt("  ...> ")
+		}
+
+		line, err := in.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if pending.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ".") {
+			if err := c.meta(ctx, strings.Fields(line)); errors.Is(err, errQuit) {
+				return nil
+			} else if err != nil {
+				fmt.Fprintf(c.out, "error: %v\n", err) //nolint:errcheck
+			}
+			continue
+		}
+
+		pending.WriteString(line)
+		pending.WriteString("\n")
+		stmts, rest := splitStatements(pending.String())
+		pending.Reset()
+		pending.WriteString(rest)
+
+		for _, stmt := range stmts {
+			if err := c.exec(ctx, stmt); err != nil {
+				fmt.Fprintf(c.out, "error: %v\n", err) //nolint:errcheck
+			}
+		}
+	}
+}
+
+// run executes every statement in src, stopping at the first error.
+func (c *console) run(ctx context.Context, src string) error {
+	stmts, rest := splitStatements(src)
+	if strings.TrimSpace(rest) != "" {
+		stmts = append(stmts, rest)
+	}
+	for _, stmt := range stmts {
+		if strings.HasPrefix(strings.TrimSpace(stmt), ".") {
+			if err := c.meta(ctx, strings.Fields(stmt)); err != nil && !errors.Is(err, errQuit) {
+				return err
+			}
+			continue
+		}
+		if err := c.exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *console) exec(ctx context.Context, stmt string) (err error) {
+	rows, err := c.pool.QueryContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var results [][]any
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		results = append(results, values)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(cols) == 0 {
+		return nil
+	}
+	return cli.WriteRows(c.out, c.mode, cols, results)
+}
+
+func (c *console) meta(ctx context.Context, args []string) error {
+	switch args[0] {
+	case ".help":
+		_, err := fmt.Fprintln(c.out, consoleHelp)
+		return err
+	case ".quit", ".exit":
+		return errQuit
+	case ".mode":
+		if len(args) == 1 {
+			_, err := fmt.Fprintln(c.out, c.mode)
+			return err
+		}
+		return c.mode.UnmarshalText([]byte(args[1]))
+	case ".tables":
+		return c.list(ctx, "SELECT name FROM pragma_table_list WHERE schema = 'main' AND type IN ('table', 'view', 'virtual') AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	case ".schema":
+		if len(args) > 1 {
+			return c.list(ctx, "SELECT sql || ';' FROM sqlite_schema WHERE sql IS NOT NULL AND tbl_name = ? ORDER BY rowid", args[1])
+		}
+		return c.list(ctx, "SELECT sql || ';' FROM sqlite_schema WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY rowid")
+	default:
+		return fmt.Errorf("unknown command %s, see .help", args[0])
+	}
+}
+
+// list prints the single text column of query one row per line.
+func (c *console) list(ctx context.Context, query string, args ...any) (err error) {
+	rows, err := c.pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(c.out, s); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// splitStatements splits src into the statements terminated by ";" outside of
+// quotes and comments, returning any unterminated remainder. As in the sqlite3
+// shell, the ";"s between a CREATE TRIGGER's BEGIN and END don't end it.
+func splitStatements(src string) (stmts []string, rest string) {
+	start := 0
+	var quote byte
+	var words []string // the first words of the statement, to spot triggers
+	depth := 0         // of BEGIN and CASE blocks in a trigger
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '[':
+			quote = ']'
+		case ch == '-' && i+1 < len(src) && src[i+1] == '-':
+			if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(src)
+			}
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(src)
+			}
+		case isWordByte(ch):
+			end := i + 1
+			for end < len(src) && isWordByte(src[end]) {
+				end++
+			}
+			word := strings.ToUpper(src[i:end])
+			if len(words) < 3 {
+				words = append(words, word)
+			}
+			if isTrigger(words) {
+				switch word {
+				case "BEGIN", "CASE":
+					depth++
+				case "END":
+					depth = max(depth-1, 0)
+				}
+			}
+			i = end - 1
+		case ch == ';':
+			if isTrigger(words) && depth > 0 {
+				continue
+			}
+			if stmt := strings.TrimSpace(src[start:i]); stmt != "" {
+				stmts = append(stmts, stmt)
+			}
+			start = i + 1
+			words, depth = nil, 0
+		}
+	}
+	return stmts, strings.TrimLeft(src[start:], " \t\r\n")
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 0x80 ||
+		'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+// isTrigger reports whether the first words of a statement create a trigger.
+func isTrigger(words []string) bool {
+	if len(words) > 1 && words[0] == "CREATE" {
+		if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+			return len(words) > 2 && words[2] == "TRIGGER"
+		}
+		return words[1] == "TRIGGER"
+	}
+	return false
+}
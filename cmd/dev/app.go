@@ -0,0 +1,312 @@
+package dev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	devserver "github.com/jonathonwebb/tilde/cmd/serve/dev"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+// mainPkg is the package go build builds the server from, with buildTags as
+// in make build.
+const (
+	mainPkg   = "./cmd"
+	buildTags = "sqlite_fts5"
+)
+
+// run runs the dev server, and the app server with rootArgs.
+func run(ctx context.Context, w io.Writer, cfg *core.Config, rootArgs []string) (err error) {
+	log := cfg.NewLogger(w, "dev")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", cfg.ServeAddr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Serve closes ln once it starts.
+		if err != nil {
+			_ = ln.Close()
+		}
+	}()
+
+	// The dev server lives here rather than in the app server, so that
+	// esbuild and browser sockets outlive restarts.
+	assets, err := devserver.NewServer(devserver.Options{
+		AssetsDir: cfg.AssetsDir,
+		StaticDir: cfg.StaticDir,
+		Logger:    log.With("component", "assets"),
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ServeShutdownGrace)
+		defer cancel()
+		err = errors.Join(err, assets.Close(ctx))
+	}()
+
+	tmp, err := os.MkdirTemp("", "tilde-dev-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, os.RemoveAll(tmp))
+	}()
+
+	appAddr, err := freeAddr()
+	if err != nil {
+		return err
+	}
+	s := &supervisor{
+		log:      log,
+		cfg:      cfg,
+		rootArgs: rootArgs,
+		out:      w,
+		bin:      filepath.Join(tmp, "tilde"),
+		addr:     appAddr,
+		socket:   devserver.SocketURL(ln.Addr()),
+	}
+
+	m := http.NewServeMux()
+	assets.Handle(m)
+	m.Handle("/", s.proxy())
+	srv := &http.Server{
+		Handler:           m,
+		ReadHeaderTimeout: 10 * time.Second,
+		ErrorLog:          slog.NewLogLogger(log.Handler(), slog.LevelWarn),
+	}
+	log.Info("starting dev server", "addr", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			log.Error("dev server stopped", "err", err)
+			stop()
+		}
+	}()
+	defer func() {
+		err = errors.Join(err, srv.Close())
+	}()
+
+	return s.run(ctx, assets)
+}
+
+// supervisor builds the app server and runs it at addr, restarting it when
+// its code changes.
+type supervisor struct {
+	log      *slog.Logger
+	cfg      *core.Config
+	rootArgs []string
+	out      io.Writer
+	bin      string
+	addr     string
+	socket   string
+
+	app *process
+}
+
+func (s *supervisor) run(ctx context.Context, assets *devserver.Server) error {
+	code := watch{root: ".", skip: s.skipDir, match: isCode}
+	templates := watch{root: s.cfg.TemplatesDir, match: func(string) bool { return true }}
+	codeStamp, err := code.snapshot()
+	if err != nil {
+		return err
+	}
+	templatesStamp, err := templates.snapshot()
+	if err != nil {
+		return err
+	}
+
+	s.restart(ctx)
+	defer s.stop()
+
+	// Changes are acted on once a poll finds nothing further changed, so that
+	// saving several files rebuilds once.
+	ticker := time.NewTicker(s.cfg.DevPoll)
+	defer ticker.Stop()
+	var codeChanged, templatesChanged bool
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("shutting down")
+			return nil
+		case <-ticker.C:
+		}
+
+		c, err := code.snapshot()
+		if err != nil {
+			s.log.Warn("check go files", "err", err)
+			continue
+		}
+		t, err := templates.snapshot()
+		if err != nil {
+			s.log.Warn("check templates", "err", err)
+			continue
+		}
+		if c != codeStamp || t != templatesStamp {
+			codeChanged = codeChanged || c != codeStamp
+			templatesChanged = templatesChanged || t != templatesStamp
+			codeStamp, templatesStamp = c, t
+			continue
+		}
+
+		switch {
+		case codeChanged:
+			s.log.Info("go files changed, rebuilding")
+			if s.restart(ctx) {
+				assets.Reload()
+			}
+		case templatesChanged:
+			// The app server re-parses templates itself in dev.
+			s.log.Info("templates changed, reloading")
+			assets.Reload()
+		}
+		codeChanged, templatesChanged = false, false
+	}
+}
+
+// restart builds the app server and replaces the running one with it, and
+// reports whether the new one is up. A failed build keeps the running one.
+func (s *supervisor) restart(ctx context.Context) bool {
+	start := time.Now()
+	next := s.bin + ".next"
+	build := exec.CommandContext(ctx, "go", "build", "-tags", buildTags, "-o", next, mainPkg)
+	out := newPrefixWriter(s.out, "[build] ")
+	build.Stdout, build.Stderr = out, out
+	err := build.Run()
+	_ = out.Flush()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error("build failed, keeping the running server", "err", err)
+		}
+		return false
+	}
+	s.log.Info("built server", "took", time.Since(start).Round(time.Millisecond))
+
+	s.stop()
+	if err := os.Rename(next, s.bin); err != nil {
+		s.log.Error("replace server binary", "err", err)
+		return false
+	}
+	app, err := startProcess(s.bin, s.args(), newPrefixWriter(s.out, "[serve] "), s.log)
+	if err != nil {
+		s.log.Error("start server", "err", err)
+		return false
+	}
+	s.app = app
+	return s.waitHealthy(ctx)
+}
+
+// waitHealthy polls the app server's health check until it passes, the server
+// exits or ctx is done.
+func (s *supervisor) waitHealthy(ctx context.Context) bool {
+	client := &http.Client{Timeout: time.Second}
+	healthz := "http://" + s.addr + "/healthz"
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthz, nil)
+		if err != nil {
+			return false
+		}
+		if resp, err := client.Do(req); err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				s.log.Info("server is healthy", "addr", s.addr)
+				return true
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.app.exited:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop stops the running app server, if any, killing it if it has not
+// drained within the grace period.
+func (s *supervisor) stop() {
+	if s.app == nil {
+		return
+	}
+	if err := s.app.stop(s.cfg.ServeShutdownGrace + time.Second); err != nil {
+		s.log.Warn("stop server", "err", err)
+	}
+	s.app = nil
+}
+
+// args returns the arguments running tilde serve -dev with the root args
+// tilde dev was given, and the environment it inherits, so that both see the
+// same config.
+func (s *supervisor) args() []string {
+	return append(slices.Clone(s.rootArgs),
+		"serve",
+		"-dev",
+		"-dev-socket="+s.socket,
+		"-addr="+s.addr,
+		"-grace="+s.cfg.ServeShutdownGrace.String(),
+	)
+}
+
+// proxy forwards requests to the app server, answering 502 while it is down.
+func (s *supervisor) proxy() http.Handler {
+	p := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: s.addr})
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		s.log.Debug("proxy", "path", r.URL.Path, "err", err)
+		http.Error(w, "tilde dev: the server is not running, see the terminal", http.StatusBadGateway)
+	}
+	return p
+}
+
+func (s *supervisor) skipDir(dir string) bool {
+	name := filepath.Base(dir)
+	if dir != "." && (name[0] == '.' || name == "node_modules") {
+		return true
+	}
+	// esbuild watches the assets itself, and templates are watched apart.
+	for _, d := range []string{s.cfg.AssetsDir, s.cfg.StaticDir, s.cfg.TemplatesDir} {
+		if filepath.Clean(d) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func isCode(name string) bool {
+	return filepath.Ext(name) == ".go" || name == "go.mod" || name == "go.sum"
+}
+
+// freeAddr returns a loopback address that is free to listen on.
+func freeAddr() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("find a port for the server: %w", err)
+	}
+	addr := ln.Addr().String()
+	return addr, ln.Close()
+}
@@ -0,0 +1,60 @@
+package dev_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/cmd/dev"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func TestSkipDir(t *testing.T) {
+	cfg := &core.Config{
+		AssetsDir:    "./ui/assets",
+		StaticDir:    "ui/static/",
+		TemplatesDir: "ui/templates",
+	}
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{".", false},
+		{"cmd/dev", false},
+		{"ui", false},
+		{".git", true},
+		{"internal/.cache", true},
+		{"node_modules", true},
+		{"ui/assets", true},
+		{"ui/static", true},
+		{"ui/templates", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := dev.SkipDir(cfg, tt.dir); got != tt.want {
+				t.Errorf("want skip = %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestArgs(t *testing.T) {
+	cfg := &core.Config{ServeShutdownGrace: 5 * time.Second}
+	serve := []string{"serve", "-dev", "-dev-socket=ws://localhost:3000/_dev/ws", "-addr=127.0.0.1:4000", "-grace=5s"}
+	tests := []struct {
+		name     string
+		rootArgs []string
+		want     []string
+	}{
+		{"no root args", []string{}, serve},
+		{"root args", []string{"-db", "dev.db", "-level=debug"}, append([]string{"-db", "dev.db", "-level=debug"}, serve...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dev.Args(cfg, tt.rootArgs, "ws://localhost:3000/_dev/ws", "127.0.0.1:4000")
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("args mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
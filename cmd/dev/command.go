@@ -0,0 +1,59 @@
+package dev
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	usage = "usage: tilde [root flags] dev [-h] [flags]"
+	help  = `usage: tilde [root flags] dev [-h] [flags]
+
+run the app server for development, rebuilding and restarting it when go
+files change. assets are rebuilt by esbuild as they change, and browsers
+reload once the restarted server is healthy, or when templates change.
+tilde dev serves at -addr, proxying to tilde serve -dev run with the same
+root flags and environment, and runs go build from the module root.
+
+flags:
+  -addr=:3000    listener address ($TLD_ADDR)
+  -grace=15s     time for the server to drain on restart ($TLD_SHUTDOWN_GRACE)
+  -poll=500ms    how often to check go files and templates for changes ($TLD_DEV_POLL)
+  -h, -help      show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "dev",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.ServeAddr, "addr", ":3000", "")
+		fs.DurationVar(&cfg.ServeShutdownGrace, "grace", 15*time.Second, "")
+		fs.DurationVar(&cfg.DevPoll, "poll", 500*time.Millisecond, "")
+	},
+	Vars: map[string]string{
+		"addr":  "TLD_ADDR",
+		"grace": "TLD_SHUTDOWN_GRACE",
+		"poll":  "TLD_DEV_POLL",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if cfg.DevPoll <= 0 {
+			e.PrintUsageErr(usage, "expected -poll > 0, but got %s", cfg.DevPoll)
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e.Stderr, cfg, e.RootArgs); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
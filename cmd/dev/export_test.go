@@ -0,0 +1,19 @@
+package dev
+
+import "github.com/jonathonwebb/tilde/internal/core"
+
+// Exports for tests of the unexported helpers.
+
+var NewPrefixWriter = newPrefixWriter
+
+func Snapshot(root string, skip func(dir string) bool, match func(name string) bool) (uint64, error) {
+	return watch{root: root, skip: skip, match: match}.snapshot()
+}
+
+func SkipDir(cfg *core.Config, dir string) bool {
+	return (&supervisor{cfg: cfg}).skipDir(dir)
+}
+
+func Args(cfg *core.Config, rootArgs []string, socket, addr string) []string {
+	return (&supervisor{cfg: cfg, rootArgs: rootArgs, socket: socket, addr: addr}).args()
+}
@@ -0,0 +1,47 @@
+package dev_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/cmd/dev"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		flush  bool
+		want   string
+	}{
+		{"lines", []string{"a\nb\n"}, false, "[x] a\n[x] b\n"},
+		{"partial lines", []string{"a", "b\nc"}, false, "[x] ab\n"},
+		{"flush partial line", []string{"a", "b\nc"}, true, "[x] ab\n[x] c\n"},
+		{"flush nothing", []string{"a\n"}, true, "[x] a\n"},
+		{"empty lines", []string{"\n\n"}, false, "[x] \n[x] \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := dev.NewPrefixWriter(&b, "[x] ")
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != len(s) {
+					t.Errorf("want %d bytes written, but got %d", len(s), n)
+				}
+			}
+			if tt.flush {
+				if err := w.Flush(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package dev
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// process is a running app server.
+type process struct {
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+func startProcess(bin string, args []string, out *prefixWriter, log *slog.Logger) (*process, error) {
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		err := cmd.Wait()
+		_ = out.Flush()
+		log.Info("server exited", "pid", cmd.Process.Pid, "status", cmd.ProcessState.String(), "err", err)
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// stop interrupts the process, as the app server drains on interrupt, and
+// kills it if it has not exited within timeout.
+func (p *process) stop(timeout time.Duration) error {
+	select {
+	case <-p.exited:
+		return nil
+	default:
+	}
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+		return p.kill()
+	}
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-p.exited:
+		return nil
+	case <-t.C:
+		return p.kill()
+	}
+}
+
+func (p *process) kill() error {
+	err := p.cmd.Process.Kill()
+	<-p.exited
+	return err
+}
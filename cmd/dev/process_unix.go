@@ -0,0 +1,15 @@
+//go:build unix
+
+package dev
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs cmd in its own process group, so that the interrupt from a
+// terminal's ^C reaches only tilde dev, which then stops the server itself.
+// Otherwise the server sees a second interrupt and exits without draining.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,47 @@
+package dev
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"io/fs"
+	"path/filepath"
+)
+
+// watch describes the files to poll for changes: those under root whose names
+// match, outside of the dirs skipped.
+type watch struct {
+	root  string
+	skip  func(dir string) bool
+	match func(name string) bool
+}
+
+// snapshot returns a hash of the paths, sizes and modification times of the
+// watched files, which changes when any is added, removed or changed.
+func (w watch) snapshot() (uint64, error) {
+	h := fnv.New64a()
+	var buf [16]byte
+	err := filepath.WalkDir(w.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if w.skip != nil && w.skip(p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !w.match(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		_, _ = h.Write([]byte(p))
+		binary.LittleEndian.PutUint64(buf[:8], uint64(info.Size()))
+		binary.LittleEndian.PutUint64(buf[8:], uint64(info.ModTime().UnixNano()))
+		_, _ = h.Write(buf[:])
+		return nil
+	})
+	return h.Sum64(), err
+}
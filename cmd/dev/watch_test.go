@@ -0,0 +1,68 @@
+package dev_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jonathonwebb/tilde/cmd/dev"
+)
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, dir string)
+		want   bool
+	}{
+		{"nothing", func(*testing.T, string) {}, false},
+		{"edit", func(t *testing.T, dir string) { write(t, dir, "main.go", "package main // edited") }, true},
+		{"add", func(t *testing.T, dir string) { write(t, dir, "pkg/new.go", "package pkg") }, true},
+		{"remove", func(t *testing.T, dir string) { remove(t, dir, "pkg/pkg.go") }, true},
+		{"unmatched file", func(t *testing.T, dir string) { write(t, dir, "notes.txt", "edited") }, false},
+		{"skipped dir", func(t *testing.T, dir string) { write(t, dir, "vendor/v.go", "package vendor // edited") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			write(t, dir, "main.go", "package main")
+			write(t, dir, "pkg/pkg.go", "package pkg")
+			write(t, dir, "notes.txt", "notes")
+			write(t, dir, "vendor/v.go", "package vendor")
+
+			snapshot := func() uint64 {
+				t.Helper()
+				s, err := dev.Snapshot(dir,
+					func(d string) bool { return filepath.Base(d) == "vendor" },
+					func(name string) bool { return filepath.Ext(name) == ".go" },
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s
+			}
+			before := snapshot()
+			tt.change(t, dir)
+			if got := snapshot() != before; got != tt.want {
+				t.Errorf("want changed = %v, but got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, dir, name, data string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remove(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.Remove(filepath.Join(dir, name)); err != nil {
+		t.Fatal(err)
+	}
+}
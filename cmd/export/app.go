@@ -0,0 +1,40 @@
+package export
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/transfer"
+)
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, format transfer.Format, table string, keys []string) (err error) {
+	log := cfg.NewLogger(e.Stderr, "export")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	t, err := transfer.Table(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	n, err := transfer.Export(ctx, db, e.Stdout, t, format, keys)
+	if err != nil {
+		return err
+	}
+	log.Info("exported rows", "table", table, "n", n)
+	return nil
+}
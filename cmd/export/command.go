@@ -0,0 +1,53 @@
+package export
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/transfer"
+)
+
+const (
+	usage = "usage: tilde [root flags] export [-h] [flags] <table> [key...]"
+	help  = `usage: tilde [root flags] export [-h] [flags] <table> [key...]
+
+write the rows of <table> to stdout. with [key...], only rows whose unique
+key (e.g. users.username, orgs.name) matches one of the keys are written.
+
+orgs are written with the username of an owner, and org_memberships with the
+org name and username in place of their ids, which differ between instances.
+org_memberships are selected by org name, and leave out deleted users and
+orgs.
+
+flags:
+  -format=ndjson   output format (ndjson|csv)
+  -h, -help        show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "export",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.TransferFormat, "format", "ndjson", "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) < 1 {
+			e.PrintUsageErr(usage, "expected <table> arg")
+			return cli.ExitUsageError
+		}
+		format, err := transfer.ParseFormat(cfg.TransferFormat)
+		if err != nil {
+			e.PrintUsageErr(usage, "invalid value %q for flag -format: %v", cfg.TransferFormat, err)
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e, cfg, format, e.Args[0], e.Args[1:]); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
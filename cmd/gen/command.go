@@ -2,6 +2,8 @@ package gen
 
 import (
 	"github.com/jonathonwebb/tilde/cmd/gen/migration"
+	"github.com/jonathonwebb/tilde/cmd/gen/models"
+	"github.com/jonathonwebb/tilde/cmd/gen/props"
 	"github.com/jonathonwebb/tilde/internal/cli"
 )
 
@@ -14,10 +16,14 @@ development template generators.
 
 commands:
   migration   generate a database migration
+  models      generate go models from the db schema
+  props       generate typescript types for island props
 
 flags:
   -h, -help   show this help and exit`,
 	Commands: []*cli.Command{
 		&migration.Cmd,
+		&models.Cmd,
+		&props.Cmd,
 	},
 }
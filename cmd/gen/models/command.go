@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+const (
+	usage = "usage: tilde [root flags] gen models [table...]"
+	help  = `usage: tilde [root flags] gen models [table...]
+
+generate go models for tables in the migrated database schema.
+
+with no [table...] args, models are generated for every table except the
+schema version tables. output is written to internal/models.
+
+flags:
+  -h, -help   show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "models",
+	Usage: usage,
+	Help:  help,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+
+		if err := run(ctx, e.Stderr, cfg, e.Args); err != nil {
+			e.PrintFailure("generate error: %v", err)
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+func run(ctx context.Context, w io.Writer, cfg *core.Config, tables []string) (err error) {
+	db, err := cfg.OpenDB(ctx, cfg.NewLogger(w, "gen"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	return schema.NewModels(ctx, "internal/models", db.Reader(), tables...)
+}
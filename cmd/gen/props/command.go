@@ -0,0 +1,59 @@
+package props
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/islands"
+	"github.com/jonathonwebb/tilde/internal/view"
+)
+
+const (
+	usage = "usage: tilde [root flags] gen props"
+	help  = `usage: tilde [root flags] gen props
+
+generate typescript types for the island props declared in internal/islands.
+output is written to props.gen.ts in the assets src dir.
+
+flags:
+  -h, -help   show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "props",
+	Usage: usage,
+	Help:  help,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		if err := run(e.Stderr, cfg); err != nil {
+			e.PrintFailure("generate error: %v", err)
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+func run(w io.Writer, cfg *core.Config) error {
+	log := cfg.NewLogger(w, "gen")
+
+	var b bytes.Buffer
+	if err := view.TypeScript(&b, islands.All...); err != nil {
+		return err
+	}
+	out := path.Join(cfg.AssetsDir, "props.gen.ts")
+	if err := os.WriteFile(out, b.Bytes(), 0644); err != nil {
+		return err
+	}
+	log.Info("generated island props", "path", out)
+	return nil
+}
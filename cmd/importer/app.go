@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/transfer"
+)
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, format transfer.Format, table, file string) (err error) {
+	log := cfg.NewLogger(e.Stderr, "import")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	var r io.Reader = e.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, f.Close())
+		}()
+		r = f
+	}
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	t, err := transfer.Table(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	stats, err := transfer.Import(ctx, db, r, t, format, transfer.ImportOptions{
+		BatchSize: cfg.TransferBatch,
+		DryRun:    cfg.TransferDryRun,
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("imported rows", "table", table, "n", stats.Rows, "batches", stats.Batches, "dry_run", cfg.TransferDryRun)
+	return nil
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/transfer"
+)
+
+const (
+	usage = "usage: tilde [root flags] import [-h] [flags] <table> [file]"
+	help  = `usage: tilde [root flags] import [-h] [flags] <table> [file]
+
+upsert rows into <table> from [file], or from stdin if [file] is omitted or
+"-". rows are matched on the table's unique key (e.g. users.username,
+orgs.name); ids are kept from the existing rows or assigned on insert.
+
+users, orgs and org_memberships are written like other changes, audited with
+the actor "import": new users and orgs are created, ones exported deleted are
+deleted, and members are added or given their exported role. orgs and
+org_memberships refer to users and orgs by name, as exported: a new org is
+created with its owner column as its first owner, and members are matched on
+their org and username columns. rows matching a deleted user or org fail until
+it is restored or purged.
+
+flags:
+  -batch=500       rows per transaction
+  -dry-run         validate and apply, then roll back
+  -format=ndjson   input format (ndjson|csv)
+  -h, -help        show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "import",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.IntVar(&cfg.TransferBatch, "batch", 500, "")
+		fs.BoolVar(&cfg.TransferDryRun, "dry-run", false, "")
+		fs.StringVar(&cfg.TransferFormat, "format", "ndjson", "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) < 1 || len(e.Args) > 2 {
+			e.PrintUsageErr(usage, "expected <table> and optional [file] args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+		format, err := transfer.ParseFormat(cfg.TransferFormat)
+		if err != nil {
+			e.PrintUsageErr(usage, "invalid value %q for flag -format: %v", cfg.TransferFormat, err)
+			return cli.ExitUsageError
+		}
+		if cfg.TransferBatch < 1 {
+			e.PrintUsageErr(usage, "expected -batch >= 1, but got %d", cfg.TransferBatch)
+			return cli.ExitUsageError
+		}
+		file := "-"
+		if len(e.Args) == 2 {
+			file = e.Args[1]
+		}
+		if err := run(ctx, e, cfg, format, e.Args[0], file); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -2,7 +2,6 @@ package migrate
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"io"
 	"strings"
@@ -22,7 +21,7 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 	}()
 
 	log.Debug("connecting to db", "path", cfg.DbConnString)
-	db, err := sql.Open("sqlite3", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
 	if err != nil {
 		return err
 	}
@@ -30,7 +29,7 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 		err = errors.Join(err, db.Close())
 	}()
 
-	store := schema.NewSqlite3SchemaStore(db, log)
+	store := schema.NewSqlite3SchemaStore(db.Writer(), log)
 	m := &schema.Migrator{
 		Store:   store,
 		Log:     log,
@@ -46,7 +45,7 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 	case core.SchemaLatest:
 		err = m.ApplyLatest(ctx)
 	case core.SchemaFile:
-		err = m.Load(ctx, strings.NewReader(schema.Schema))
+		err = m.Load(ctx, strings.NewReader(schema.Schema), schema.SchemaVersion)
 	default:
 		err = m.Apply(ctx, int64(cfg.DbSchemaVersion))
 	}
@@ -0,0 +1,70 @@
+package orgs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/models"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type application struct {
+	log   *slog.Logger
+	users *store.UserStore
+	orgs  *store.OrgStore
+}
+
+// run opens the database and calls fn with the stores, logging any error.
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, fn func(context.Context, *application) error) (err error) {
+	log := cfg.NewLogger(e.Stderr, "orgs")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	ctx = core.WithActor(ctx, e.Actor())
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return err
+	}
+
+	return fn(ctx, &application{log: log, users: store.NewUserStore(db), orgs: store.NewOrgStore(db)})
+}
+
+// lookup resolves an org name and username to their ids.
+func (app *application) lookup(ctx context.Context, org, user string) (orgId, userId int64, err error) {
+	o, err := app.orgs.GetByName(ctx, org)
+	if err != nil {
+		return 0, 0, err
+	}
+	u, err := app.users.GetByName(ctx, user)
+	if err != nil {
+		return 0, 0, err
+	}
+	return o.Id, u.Id, nil
+}
+
+func writeOrgs(w io.Writer, format cli.OutputFormat, orgs ...models.Org) error {
+	rows := make([][]any, len(orgs))
+	for i, o := range orgs {
+		rows[i] = []any{o.Id, o.Name}
+	}
+	return cli.WriteRows(w, format, []string{"id", "name"}, rows)
+}
@@ -0,0 +1,41 @@
+package orgs
+
+import (
+	"github.com/jonathonwebb/tilde/internal/cli"
+)
+
+var Cmd = cli.Command{
+	Name:  "orgs",
+	Usage: "usage: tilde [root flags] orgs <command>",
+	Help: `usage: tilde [root flags] orgs <command>
+
+manage orgs and their members. commands refuse to run unless the database
+schema is at the latest migration.
+
+commands:
+  add-member      add a user to an org
+  create          create an org
+  delete          delete an org
+  list            list orgs
+  members         list the members of an org
+  remove-member   remove a user from an org
+  rename          rename an org
+  restore         restore a deleted org
+  set-role        change a member's role
+  show            show an org
+
+flags:
+  -h, -help       show this help and exit`,
+	Commands: []*cli.Command{
+		&addMemberCmd,
+		&createOrgCmd,
+		&deleteOrgCmd,
+		&listOrgsCmd,
+		&membersCmd,
+		&removeMemberCmd,
+		&renameOrgCmd,
+		&restoreOrgCmd,
+		&setRoleCmd,
+		&showOrgCmd,
+	},
+}
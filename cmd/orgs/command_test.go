@@ -0,0 +1,95 @@
+package orgs_test
+
+import (
+	"database/sql"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/cmd/orgs"
+	"github.com/jonathonwebb/tilde/cmd/users"
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+func TestOrgsCommand(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	migrate(t, dbPath)
+
+	e, cfg, errBuf, _ := setUp(t, dbPath, "create", "alice")
+	e.Args[0] = "users"
+	if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+		t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+	}
+
+	t.Run("create without owner", func(t *testing.T) {
+		e, cfg, errBuf, _ := setUp(t, dbPath, "create", "acme")
+
+		if got := orgs.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitUsageError {
+			t.Errorf("want exit status = %v, but got %v", cli.ExitUsageError, got)
+		}
+		if !strings.Contains(errBuf.String(), "expected -owner flag") {
+			t.Errorf("want missing owner error, but got %q", errBuf.String())
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		e, cfg, errBuf, _ := setUp(t, dbPath, "create", "-owner=alice", "acme")
+		if got := orgs.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+		}
+
+		e, cfg, errBuf, outBuf := setUp(t, dbPath, "members", "-format=csv", "acme")
+		if got := orgs.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+		}
+
+		want := "id,username,role\n1,alice,owner\n"
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("out output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func migrate(t testing.TB, dbPath string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.DiscardHandler)
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db, log), Log: log, Sources: migrations.All}
+	if err := m.ApplyLatest(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setUp(t testing.TB, dbPath string, args ...string) (*cli.Env, *core.Config, *strings.Builder, *strings.Builder) {
+	t.Helper()
+
+	var errBuf, outBuf strings.Builder
+
+	return &cli.Env{
+			Log:    log.New(&errBuf, "", 0),
+			Stderr: &errBuf,
+			Stdout: &outBuf,
+			Args:   append([]string{"orgs"}, args...),
+		}, &core.Config{
+			Env:           "test",
+			Level:         slog.LevelError,
+			Format:        core.JSONFormat,
+			DbConnString:  dbPath,
+			DbForeignKeys: true,
+		},
+		&errBuf,
+		&outBuf
+}
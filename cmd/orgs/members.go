@@ -0,0 +1,171 @@
+package orgs
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	addMemberUsage = "usage: tilde [root flags] orgs add-member [-h] [flags] <org> <user>"
+	addMemberHelp  = `usage: tilde [root flags] orgs add-member [-h] [flags] <org> <user>
+
+add <user> to <org>.
+
+flags:
+  -role=member   member role (owner|admin|member)
+  -h, -help      show this help and exit`
+
+	membersUsage = "usage: tilde [root flags] orgs members [-h] [flags] <org>"
+	membersHelp  = `usage: tilde [root flags] orgs members [-h] [flags] <org>
+
+list the members of <org> and their roles.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	removeMemberUsage = "usage: tilde [root flags] orgs remove-member [-h] <org> <user>"
+	removeMemberHelp  = `usage: tilde [root flags] orgs remove-member [-h] <org> <user>
+
+remove <user> from <org>. the last owner of an org cannot be removed.
+
+flags:
+  -h, -help   show this help and exit`
+
+	setRoleUsage = "usage: tilde [root flags] orgs set-role [-h] <org> <user> <role>"
+	setRoleHelp  = `usage: tilde [root flags] orgs set-role [-h] <org> <user> <role>
+
+change the role of <user> in <org> to <role> (owner|admin|member). the last
+owner of an org cannot be demoted.
+
+flags:
+  -h, -help   show this help and exit`
+)
+
+var addMemberCmd = cli.Command{
+	Name:  "add-member",
+	Usage: addMemberUsage,
+	Help:  addMemberHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.MemberRole, "role", string(store.RoleMember), "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 2 {
+			e.PrintUsageErr(addMemberUsage, "expected <org> and <user> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+		role, err := store.ParseRole(cfg.MemberRole)
+		if err != nil {
+			e.PrintUsageErr(addMemberUsage, "invalid value %q for flag -role: %v", cfg.MemberRole, err)
+			return cli.ExitUsageError
+		}
+
+		err = run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			orgId, userId, err := app.lookup(ctx, e.Args[0], e.Args[1])
+			if err != nil {
+				return err
+			}
+			return app.orgs.AddMember(ctx, orgId, userId, role)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var membersCmd = cli.Command{
+	Name:  "members",
+	Usage: membersUsage,
+	Help:  membersHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(membersUsage, "expected <org> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			o, err := app.orgs.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			members, err := app.orgs.Members(ctx, o.Id)
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]any, len(members))
+			for i, m := range members {
+				rows[i] = []any{m.User.Id, m.User.Username, string(m.Role)}
+			}
+			return cli.WriteRows(e.Stdout, cfg.Output, []string{"id", "username", "role"}, rows)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var removeMemberCmd = cli.Command{
+	Name:  "remove-member",
+	Usage: removeMemberUsage,
+	Help:  removeMemberHelp,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 2 {
+			e.PrintUsageErr(removeMemberUsage, "expected <org> and <user> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			orgId, userId, err := app.lookup(ctx, e.Args[0], e.Args[1])
+			if err != nil {
+				return err
+			}
+			return app.orgs.RemoveMember(ctx, orgId, userId)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var setRoleCmd = cli.Command{
+	Name:  "set-role",
+	Usage: setRoleUsage,
+	Help:  setRoleHelp,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 3 {
+			e.PrintUsageErr(setRoleUsage, "expected <org>, <user> and <role> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+		role, err := store.ParseRole(e.Args[2])
+		if err != nil {
+			e.PrintUsageErr(setRoleUsage, "invalid role %q: %v", e.Args[2], err)
+			return cli.ExitUsageError
+		}
+
+		err = run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			orgId, userId, err := app.lookup(ctx, e.Args[0], e.Args[1])
+			if err != nil {
+				return err
+			}
+			return app.orgs.SetRole(ctx, orgId, userId, role)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
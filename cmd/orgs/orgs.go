@@ -0,0 +1,256 @@
+package orgs
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	createUsage = "usage: tilde [root flags] orgs create [-h] [flags] -owner=<user> <name>"
+	createHelp  = `usage: tilde [root flags] orgs create [-h] [flags] -owner=<user> <name>
+
+create an org named <name>, owned by <user>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -owner=<user>   username of the org's first owner (required)
+  -h, -help       show this help and exit`
+
+	deleteUsage = "usage: tilde [root flags] orgs delete [-h] <name>"
+	deleteHelp  = `usage: tilde [root flags] orgs delete [-h] <name>
+
+delete the org named <name>, hiding it and its memberships until it is
+restored or purged.
+
+flags:
+  -h, -help   show this help and exit`
+
+	listUsage = "usage: tilde [root flags] orgs list [-h] [flags]"
+	listHelp  = `usage: tilde [root flags] orgs list [-h] [flags]
+
+list orgs ordered by id. when more orgs remain, the -after value for the
+next page is logged.
+
+flags:
+  -after=0        list orgs with ids greater than this
+  -format=table   output format (table|json|csv)
+  -limit=100      maximum number of orgs to list
+  -h, -help       show this help and exit`
+
+	renameUsage = "usage: tilde [root flags] orgs rename [-h] [flags] <name> <new-name>"
+	renameHelp  = `usage: tilde [root flags] orgs rename [-h] [flags] <name> <new-name>
+
+rename <name> to <new-name>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	restoreUsage = "usage: tilde [root flags] orgs restore [-h] [flags] <name>"
+	restoreHelp  = `usage: tilde [root flags] orgs restore [-h] [flags] <name>
+
+restore the deleted org named <name>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	showUsage = "usage: tilde [root flags] orgs show [-h] [flags] <name>"
+	showHelp  = `usage: tilde [root flags] orgs show [-h] [flags] <name>
+
+show the org named <name>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+)
+
+func formatFlag(fs *flag.FlagSet, target any) {
+	cfg := target.(*core.Config)
+	fs.TextVar(&cfg.Output, "format", &cli.TableOutput, "")
+}
+
+var createOrgCmd = cli.Command{
+	Name:  "create",
+	Usage: createUsage,
+	Help:  createHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		formatFlag(fs, target)
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.OrgOwner, "owner", "", "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(createUsage, "expected <name> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if cfg.OrgOwner == "" {
+			e.PrintUsageErr(createUsage, "expected -owner flag")
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			owner, err := app.users.GetByName(ctx, cfg.OrgOwner)
+			if err != nil {
+				return err
+			}
+			o, err := app.orgs.Create(ctx, e.Args[0], owner.Id)
+			if err != nil {
+				return err
+			}
+			return writeOrgs(e.Stdout, cfg.Output, *o)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var deleteOrgCmd = cli.Command{
+	Name:  "delete",
+	Usage: deleteUsage,
+	Help:  deleteHelp,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(deleteUsage, "expected <name> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			o, err := app.orgs.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			return app.orgs.Delete(ctx, o.Id)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var listOrgsCmd = cli.Command{
+	Name:  "list",
+	Usage: listUsage,
+	Help:  listHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		formatFlag(fs, target)
+		fs.Int64Var(&cfg.ListAfter, "after", 0, "")
+		fs.IntVar(&cfg.ListLimit, "limit", store.DefaultLimit, "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(listUsage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			page, err := app.orgs.List(ctx, cfg.ListAfter, cfg.ListLimit)
+			if err != nil {
+				return err
+			}
+			if page.Next != 0 {
+				app.log.Info("more orgs", "after", page.Next)
+			}
+			return writeOrgs(e.Stdout, cfg.Output, page.Items...)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var renameOrgCmd = cli.Command{
+	Name:  "rename",
+	Usage: renameUsage,
+	Help:  renameHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 2 {
+			e.PrintUsageErr(renameUsage, "expected <name> and <new-name> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			o, err := app.orgs.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			if o, err = app.orgs.Rename(ctx, o.Id, e.Args[1]); err != nil {
+				return err
+			}
+			return writeOrgs(e.Stdout, cfg.Output, *o)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var showOrgCmd = cli.Command{
+	Name:  "show",
+	Usage: showUsage,
+	Help:  showHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(showUsage, "expected <name> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			o, err := app.orgs.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			return writeOrgs(e.Stdout, cfg.Output, *o)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var restoreOrgCmd = cli.Command{
+	Name:  "restore",
+	Usage: restoreUsage,
+	Help:  restoreHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(restoreUsage, "expected <name> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			o, err := app.orgs.GetDeletedByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			if o, err = app.orgs.Restore(ctx, o.Id); err != nil {
+				return err
+			}
+			return writeOrgs(e.Stdout, cfg.Output, *o)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -0,0 +1,50 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config) (err error) {
+	log := cfg.NewLogger(e.Stderr, "purge")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	ctx = core.WithActor(ctx, e.Actor())
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return err
+	}
+
+	before := time.Now().Add(-cfg.PurgeRetain)
+	users, err := store.NewUserStore(db).Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+	orgs, err := store.NewOrgStore(db).Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+	log.Info("purged deleted rows", "before", before.UTC(), "users", users, "orgs", orgs)
+	return nil
+}
@@ -0,0 +1,46 @@
+package purge
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	usage = "usage: tilde [root flags] purge [-h] [flags]"
+	help  = `usage: tilde [root flags] purge [-h] [flags]
+
+permanently remove users and orgs that were deleted longer ago than the
+retention window.
+
+flags:
+  -retain=720h   deleted row retention window ($TLD_PURGE_RETAIN)
+  -h, -help      show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "purge",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.DurationVar(&cfg.PurgeRetain, "retain", store.DefaultPurgeRetain, "")
+	},
+	Vars: map[string]string{
+		"retain": "TLD_PURGE_RETAIN",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e, cfg); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
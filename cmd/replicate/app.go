@@ -0,0 +1,44 @@
+package replicate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jonathonwebb/tilde/internal/backup"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
+	log := cfg.NewLogger(w, "replicate")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	r := &backup.Replicator{
+		DB:       db,
+		Dir:      cfg.ReplicaDir,
+		Interval: cfg.ReplicaInterval,
+		Snapshot: cfg.ReplicaSnapshot,
+		Retain:   cfg.ReplicaRetain,
+		Log:      log,
+	}
+	return r.Run(ctx)
+}
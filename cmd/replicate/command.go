@@ -0,0 +1,65 @@
+package replicate
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	usage = "usage: tilde [root flags] replicate [-h] [flags]"
+	help  = `usage: tilde [root flags] replicate [-h] [flags]
+
+continuously replicate the database to a replica dir until interrupted.
+a snapshot is written every -interval when the database has changed. every
+-snapshot it is a full copy of the database, and otherwise it holds only the
+pages changed since the snapshot before. full snapshots and the incremental
+ones after them are removed once a newer full snapshot is older than -retain,
+so the replica dir holds about -retain / -snapshot + 1 full copies and the
+pages changed since. restore a point in time with
+tilde restore -dir=<replica dir> -at=<time>.
+
+flags:
+  -dir=replica    replica dir ($TLD_REPLICA_DIR)
+  -interval=1m    snapshot interval ($TLD_REPLICA_INTERVAL)
+  -retain=168h    snapshot retention window ($TLD_REPLICA_RETAIN)
+  -snapshot=24h   full snapshot interval ($TLD_REPLICA_SNAPSHOT)
+  -h, -help       show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "replicate",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.StringVar(&cfg.ReplicaDir, "dir", "replica", "")
+		fs.DurationVar(&cfg.ReplicaInterval, "interval", time.Minute, "")
+		fs.DurationVar(&cfg.ReplicaRetain, "retain", 7*24*time.Hour, "")
+		fs.DurationVar(&cfg.ReplicaSnapshot, "snapshot", 24*time.Hour, "")
+	},
+	Vars: map[string]string{
+		"dir":      "TLD_REPLICA_DIR",
+		"interval": "TLD_REPLICA_INTERVAL",
+		"retain":   "TLD_REPLICA_RETAIN",
+		"snapshot": "TLD_REPLICA_SNAPSHOT",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(usage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if cfg.ReplicaInterval <= 0 {
+			e.PrintUsageErr(usage, "expected -interval > 0, but got %s", cfg.ReplicaInterval)
+			return cli.ExitUsageError
+		}
+		if err := run(ctx, e.Stderr, cfg); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
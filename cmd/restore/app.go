@@ -0,0 +1,43 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/jonathonwebb/tilde/internal/backup"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+)
+
+func run(ctx context.Context, w io.Writer, cfg *core.Config, manifest string) (err error) {
+	log := cfg.NewLogger(w, "restore")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	if manifest == "" {
+		manifest, err = backup.Find(cfg.BackupDir, cfg.RestoreAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m, err := backup.Restore(ctx, db, manifest, migrations.All)
+	if err != nil {
+		return err
+	}
+	log.Info("restored backup", "file", m.File, "schema", m.SchemaVersion, "created", m.CreatedAt)
+	return nil
+}
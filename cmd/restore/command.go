@@ -0,0 +1,58 @@
+package restore
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+const (
+	usage = "usage: tilde [root flags] restore [-h] [flags] [manifest]"
+	help  = `usage: tilde [root flags] restore [-h] [flags] [manifest]
+
+restore the database from the backup described by [manifest], or from the
+newest backup in -dir. with -at, the newest backup taken at or before the
+given time is used, so a replica dir can be restored to a point in time.
+the backup's schema version must be known to this binary's migrations.
+
+flags:
+  -at=<time>     restore to an RFC 3339 timestamp
+  -dir=backups   backup or replica dir ($TLD_BACKUP_DIR)
+  -h, -help      show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "restore",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.TextVar(&cfg.RestoreAt, "at", time.Time{}, "")
+		fs.StringVar(&cfg.BackupDir, "dir", "backups", "")
+	},
+	Vars: map[string]string{
+		"dir": "TLD_BACKUP_DIR",
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) > 1 {
+			e.PrintUsageErr(usage, "expected at most 1 [manifest] arg, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+		if len(e.Args) == 1 && !cfg.RestoreAt.IsZero() {
+			e.PrintUsageErr(usage, "expected either [manifest] or -at, but got both")
+			return cli.ExitUsageError
+		}
+		var manifest string
+		if len(e.Args) == 1 {
+			manifest = e.Args[0]
+		}
+		if err := run(ctx, e.Stderr, cfg, manifest); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
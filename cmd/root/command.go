@@ -3,11 +3,25 @@ package root
 import (
 	"flag"
 	"log/slog"
+	"time"
 
 	"github.com/jonathonwebb/tilde/cmd/assets"
+	"github.com/jonathonwebb/tilde/cmd/audit"
+	"github.com/jonathonwebb/tilde/cmd/authz"
+	"github.com/jonathonwebb/tilde/cmd/backup"
+	"github.com/jonathonwebb/tilde/cmd/db"
+	"github.com/jonathonwebb/tilde/cmd/dev"
+	"github.com/jonathonwebb/tilde/cmd/export"
 	"github.com/jonathonwebb/tilde/cmd/gen"
+	"github.com/jonathonwebb/tilde/cmd/importer"
 	"github.com/jonathonwebb/tilde/cmd/migrate"
+	"github.com/jonathonwebb/tilde/cmd/orgs"
+	"github.com/jonathonwebb/tilde/cmd/purge"
+	"github.com/jonathonwebb/tilde/cmd/replicate"
+	"github.com/jonathonwebb/tilde/cmd/restore"
+	"github.com/jonathonwebb/tilde/cmd/search"
 	"github.com/jonathonwebb/tilde/cmd/serve"
+	"github.com/jonathonwebb/tilde/cmd/users"
 	"github.com/jonathonwebb/tilde/cmd/version"
 	"github.com/jonathonwebb/tilde/internal/cli"
 	"github.com/jonathonwebb/tilde/internal/core"
@@ -21,36 +35,73 @@ var Cmd = cli.Command{
 utils for managing the tilde application.
 
 commands:
-  assets    compile frontend assets
-  gen       generate dev templates
-  migrate   update database schema
-  serve     start app server
-  version   print version info
+  assets      compile frontend assets
+  audit       inspect the audit log
+  authz       inspect authorization decisions
+  backup      back up the database
+  db          open a sql console
+  dev         run the app server, rebuilding on change
+  export      export table rows
+  gen         generate dev templates
+  import      import table rows
+  migrate     update database schema
+  orgs        manage orgs and members
+  purge       remove expired deleted users and orgs
+  replicate   continuously replicate the database
+  restore     restore the database from a backup
+  search      search users and orgs
+  serve       start app server
+  users       manage users
+  version     print version info
 
 flags:
-  -assets=ui/assets   assets src dir ($TLD_ASSETS)
-  -db=data.db         sqlite connection string ($TLD_DB)
-  -env=production     app env id ($TLD_ENV)
-  -format=text        log format (text|json) ($TLD_FMT)
-  -level=info         log level (debug|info|warn|error) ($TLD_LVL)
-  -public=ui/static   public asset dir ($TLD_PUBLIC)
-  -h, -help           show this help and exit`,
+  -assets=ui/assets         assets src dir ($TLD_ASSETS)
+  -db=data.db               sqlite connection string ($TLD_DB)
+  -db-busy-timeout=5s       wait for locks before failing ($TLD_DB_BUSY_TIMEOUT)
+  -db-cache-size=-20000     page cache size, in KiB if negative ($TLD_DB_CACHE_SIZE)
+  -db-foreign-keys=true     enforce foreign key constraints ($TLD_DB_FOREIGN_KEYS)
+  -db-journal=wal           journal mode (wal|delete|truncate|persist|memory|off) ($TLD_DB_JOURNAL)
+  -db-max-conns=8           max open connections ($TLD_DB_MAX_CONNS)
+  -db-slow=200ms            log statements slower than this as warnings ($TLD_DB_SLOW)
+  -db-sync=normal           synchronous mode (off|normal|full|extra) ($TLD_DB_SYNC)
+  -env=production           app env id ($TLD_ENV)
+  -format=text              log format (text|json) ($TLD_FMT)
+  -level=info               log level (debug|info|warn|error) ($TLD_LVL)
+  -public=ui/static         public asset dir ($TLD_PUBLIC)
+  -templates=ui/templates   html template dir ($TLD_TEMPLATES)
+  -h, -help                 show this help and exit`,
 	Flags: func(fs *flag.FlagSet, target any) {
 		cfg := target.(*core.Config)
 		fs.StringVar(&cfg.AssetsDir, "assets", "ui/assets", "")
 		fs.StringVar(&cfg.DbConnString, "db", "data.db", "")
+		fs.DurationVar(&cfg.DbBusyTimeout, "db-busy-timeout", 5*time.Second, "")
+		fs.IntVar(&cfg.DbCacheSize, "db-cache-size", -20000, "")
+		fs.BoolVar(&cfg.DbForeignKeys, "db-foreign-keys", true, "")
+		fs.StringVar(&cfg.DbJournalMode, "db-journal", "wal", "")
+		fs.IntVar(&cfg.DbMaxConns, "db-max-conns", 8, "")
+		fs.DurationVar(&cfg.DbSlowQuery, "db-slow", 200*time.Millisecond, "")
+		fs.StringVar(&cfg.DbSynchronous, "db-sync", "normal", "")
 		fs.StringVar(&cfg.Env, "env", "production", "")
 		fs.TextVar(&cfg.Format, "format", &core.TextFormat, "")
 		fs.TextVar(&cfg.Level, "level", slog.LevelInfo, "")
 		fs.StringVar(&cfg.StaticDir, "public", "ui/static", "")
+		fs.StringVar(&cfg.TemplatesDir, "templates", "ui/templates", "")
 	},
 	Vars: map[string]string{
-		"assets": "TLD_ASSETS",
-		"db":     "TLD_DB",
-		"env":    "TLD_ENV",
-		"format": "TLD_FMT",
-		"level":  "TLD_LVL",
-		"public": "TLD_PUBLIC",
+		"assets":          "TLD_ASSETS",
+		"db":              "TLD_DB",
+		"db-busy-timeout": "TLD_DB_BUSY_TIMEOUT",
+		"db-cache-size":   "TLD_DB_CACHE_SIZE",
+		"db-foreign-keys": "TLD_DB_FOREIGN_KEYS",
+		"db-journal":      "TLD_DB_JOURNAL",
+		"db-max-conns":    "TLD_DB_MAX_CONNS",
+		"db-slow":         "TLD_DB_SLOW",
+		"db-sync":         "TLD_DB_SYNC",
+		"env":             "TLD_ENV",
+		"format":          "TLD_FMT",
+		"level":           "TLD_LVL",
+		"public":          "TLD_PUBLIC",
+		"templates":       "TLD_TEMPLATES",
 	},
-	Commands: []*cli.Command{&assets.Cmd, &gen.Cmd, &migrate.Cmd, &serve.Cmd, &version.Cmd},
+	Commands: []*cli.Command{&assets.Cmd, &audit.Cmd, &authz.Cmd, &backup.Cmd, &db.Cmd, &dev.Cmd, &export.Cmd, &gen.Cmd, &importer.Cmd, &migrate.Cmd, &orgs.Cmd, &purge.Cmd, &replicate.Cmd, &restore.Cmd, &search.Cmd, &serve.Cmd, &users.Cmd, &version.Cmd},
 }
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, query string, kinds []store.Kind) (err error) {
+	log := cfg.NewLogger(e.Stderr, "search")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return err
+	}
+
+	results, err := store.NewSearchStore(db).Search(ctx, query, kinds...)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]any, len(results))
+	for i, r := range results {
+		rows[i] = []any{string(r.Kind), r.Id, r.Name, r.Highlight("[", "]")}
+	}
+	return cli.WriteRows(e.Stdout, cfg.Output, []string{"kind", "id", "name", "match"}, rows)
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	usage = "usage: tilde [root flags] search [-h] [flags] <query>"
+	help  = `usage: tilde [root flags] search [-h] [flags] <query>
+
+search users and orgs by name, best matches first. each word of <query>
+matches any word starting with it, and matches are shown in [brackets].
+
+flags:
+  -format=table      output format (table|json|csv)
+  -kind=<kind,...>   only search these kinds (user|org)
+  -h, -help          show this help and exit`
+)
+
+var Cmd = cli.Command{
+	Name:  "search",
+	Usage: usage,
+	Help:  help,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		fs.TextVar(&cfg.Output, "format", &cli.TableOutput, "")
+		fs.StringVar(&cfg.SearchKinds, "kind", "", "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) == 0 {
+			e.PrintUsageErr(usage, "expected a query")
+			return cli.ExitUsageError
+		}
+
+		var kinds []store.Kind
+		if cfg.SearchKinds != "" {
+			for _, s := range strings.Split(cfg.SearchKinds, ",") {
+				kind, err := store.ParseKind(s)
+				if err != nil {
+					e.PrintUsageErr(usage, "invalid value %q for flag -kind: %v", cfg.SearchKinds, err)
+					return cli.ExitUsageError
+				}
+				kinds = append(kinds, kind)
+			}
+		}
+
+		if err := run(ctx, e, cfg, strings.Join(e.Args, " "), kinds); err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
@@ -1,12 +1,27 @@
 package serve
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/jonathonwebb/tilde/cmd/serve/dev"
+	"github.com/jonathonwebb/tilde/internal/authz"
+	"github.com/jonathonwebb/tilde/internal/backup"
 	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/islands"
+	"github.com/jonathonwebb/tilde/internal/static"
+	"github.com/jonathonwebb/tilde/internal/store"
+	"github.com/jonathonwebb/tilde/internal/view"
 )
 
 func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
@@ -17,25 +32,277 @@ func run(ctx context.Context, w io.Writer, cfg *core.Config) (err error) {
 		}
 	}()
 
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+			return
+		}
+		log.Info("closed db")
+	}()
+	if cfg.ReplicaDir != "" && db.InMemory() {
+		return errors.New("can't replicate an in-memory database, unset -replica-dir")
+	}
+
+	ln, err := net.Listen("tcp", cfg.ServeAddr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Serve closes ln once it starts.
+		if err != nil {
+			_ = ln.Close()
+		}
+	}()
+
+	// tilde dev runs the dev server itself, so that it outlives restarts of
+	// this one, and passes its socket URL.
+	var devServer *dev.Server
+	devSocket := cfg.ServeDevSocket
+	if cfg.ServeDev && devSocket == "" {
+		devSocket = dev.SocketURL(ln.Addr())
+		devServer, err = dev.NewServer(dev.Options{
+			AssetsDir: cfg.AssetsDir,
+			StaticDir: cfg.StaticDir,
+			Logger:    log.With("component", "dev"),
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ServeShutdownGrace)
+			defer cancel()
+			if closeErr := devServer.Close(ctx); closeErr != nil {
+				err = errors.Join(err, closeErr)
+				return
+			}
+			log.Info("stopped dev server")
+		}()
+	}
+
+	views, err := view.New(view.Options{
+		Templates:    os.DirFS(cfg.TemplatesDir),
+		Static:       os.DirFS(cfg.StaticDir),
+		Dev:          cfg.ServeDev,
+		DevSocketURL: devSocket,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Background work stops once the server has drained, and is waited for so
+	// that it never outlives the database.
+	var wg sync.WaitGroup
+	bgCtx, cancelBg := context.WithCancel(context.WithoutCancel(ctx))
+	defer func() {
+		cancelBg()
+		wg.Wait()
+		log.Info("stopped background jobs")
+	}()
+	background := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	if cfg.ReplicaDir != "" {
+		r := &backup.Replicator{
+			DB:       db,
+			Dir:      cfg.ReplicaDir,
+			Interval: cfg.ReplicaInterval,
+			Snapshot: cfg.ReplicaSnapshot,
+			Retain:   cfg.ReplicaRetain,
+			Log:      log.With("component", "replica"),
+		}
+		background(func() {
+			if err := r.Run(bgCtx); err != nil {
+				log.Error("replication stopped", "err", err)
+			}
+		})
+	}
+
+	jobCtx := core.WithActor(bgCtx, "system")
+	if cfg.AuditRetain > 0 {
+		audit := store.NewAuditStore(db)
+		log := log.With("component", "audit")
+		background(func() {
+			hourly(jobCtx, func(ctx context.Context) {
+				n, err := audit.Prune(ctx, time.Now().Add(-cfg.AuditRetain))
+				if err != nil {
+					log.Error("prune audit events", "err", err)
+				} else if n > 0 {
+					log.Info("pruned audit events", "n", n)
+				}
+			})
+		})
+	}
+	if cfg.PurgeRetain > 0 {
+		users, orgs := store.NewUserStore(db), store.NewOrgStore(db)
+		log := log.With("component", "purge")
+		background(func() {
+			hourly(jobCtx, func(ctx context.Context) {
+				before := time.Now().Add(-cfg.PurgeRetain)
+				u, err := users.Purge(ctx, before)
+				if err != nil {
+					log.Error("purge deleted users", "err", err)
+				}
+				o, err := orgs.Purge(ctx, before)
+				if err != nil {
+					log.Error("purge deleted orgs", "err", err)
+				}
+				if u > 0 || o > 0 {
+					log.Info("purged deleted rows", "users", u, "orgs", o)
+				}
+			})
+		})
+	}
+
 	app := &application{
-		log: log,
+		log:   log,
+		cfg:   cfg,
+		db:    db,
+		views: views,
+		dev:   devServer,
+		users: store.NewUserStore(db),
+		authz: &authz.Authorizer{Policy: authz.DefaultPolicy, Roles: store.NewOrgStore(db)},
 	}
 
-	log.Info("starting server", "addr", cfg.ServeAddr, "dev", cfg.ServeDev)
-	return http.ListenAndServe(cfg.ServeAddr, app.handlers())
+	srv := &http.Server{
+		Handler:           app.handlers(),
+		ReadTimeout:       cfg.ServeReadTimeout,
+		ReadHeaderTimeout: cfg.ServeReadTimeout,
+		WriteTimeout:      cfg.ServeWriteTimeout,
+		IdleTimeout:       cfg.ServeIdleTimeout,
+		ErrorLog:          slog.NewLogLogger(log.Handler(), slog.LevelWarn),
+	}
+	log.Info("starting server", "addr", ln.Addr().String(), "dev", cfg.ServeDev)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal kills the process rather than waiting out the drain.
+	stop()
+
+	log.Info("shutting down, draining connections", "grace", cfg.ServeShutdownGrace)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.ServeShutdownGrace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warn("grace period expired, closing remaining connections", "err", err)
+		if err := srv.Close(); err != nil {
+			return err
+		}
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Info("server stopped")
+	return nil
 }
 
 type application struct {
-	log *slog.Logger
+	log   *slog.Logger
+	cfg   *core.Config
+	db    *core.DB
+	views *view.View
+	dev   *dev.Server
+	users *store.UserStore
+	authz *authz.Authorizer
 }
 
 func (app *application) handlers() http.Handler {
 	m := http.NewServeMux()
 	m.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
-		_, err := w.Write([]byte("hello, world!"))
-		if err != nil {
-			w.WriteHeader(500)
+		app.render(w, r, http.StatusOK, "home", struct{ App islands.AppProps }{
+			App: islands.AppProps{Name: "world"},
+		})
+	})
+	m.Handle("GET /search", app.authz.Require(authz.Read, authz.KindOf("search"), http.HandlerFunc(app.search)))
+	m.Handle("GET /public/", http.StripPrefix("/public", static.Handler(os.DirFS(app.cfg.StaticDir))))
+	if app.dev != nil {
+		app.dev.Handle(m)
+	}
+	m.HandleFunc("GET /healthz", app.health)
+	return requestId(app.subject(m))
+}
+
+// health reports whether the server can reach the database, for tilde dev and
+// load balancers to tell when it is ready.
+func (app *application) health(w http.ResponseWriter, r *http.Request) {
+	if err := app.db.Reader().PingContext(r.Context()); err != nil {
+		app.log.Error("health check", "err", err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// render writes page as the response, or a 500 if it fails to render.
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	var buf bytes.Buffer
+	if err := app.views.Render(&buf, page, data); err != nil {
+		app.log.Error("render page", "page", page, "path", r.URL.Path, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		app.log.Debug("write page", "page", page, "err", err)
+	}
+}
+
+// requestId tags each request with an id, reusing a well-formed X-Request-Id
+// from a proxy, so that logs and audit events can be correlated.
+func requestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		if !validRequestId(id) {
+			id = core.NewRequestId()
 		}
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(core.WithRequestId(r.Context(), id)))
 	})
-	return m
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// hourly runs job now and then every hour until ctx is done.
+func hourly(ctx context.Context, job func(context.Context)) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		job(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
@@ -3,6 +3,7 @@ package serve
 import (
 	"context"
 	"flag"
+	"time"
 
 	"github.com/jonathonwebb/tilde/internal/cli"
 	"github.com/jonathonwebb/tilde/internal/core"
@@ -16,17 +17,62 @@ var Cmd = cli.Command{
 starts the tilde application server.
 
 flags:
-  -addr=:0     listener address ($TLD_ADDR)
-  -dev         enable dev server
-  -h, --help   show this help and exit`,
+  -addr=:0                listener address ($TLD_ADDR)
+  -audit-retain=0         prune audit events older than this, 0 to keep all ($TLD_AUDIT_RETAIN)
+  -dev                    enable dev server
+  -dev-socket=<url>       use the dev server of tilde dev at url
+  -grace=15s              time to drain connections on shutdown ($TLD_SHUTDOWN_GRACE)
+  -idle-timeout=2m        keep-alive connection idle timeout ($TLD_IDLE_TIMEOUT)
+  -purge-retain=0         purge users and orgs deleted longer ago, 0 to keep all ($TLD_PURGE_RETAIN)
+  -read-timeout=10s       request read timeout, headers included ($TLD_READ_TIMEOUT)
+  -replica-dir=<dir>      replicate the database to dir ($TLD_REPLICA_DIR)
+  -replica-interval=1m    replica snapshot interval ($TLD_REPLICA_INTERVAL)
+  -replica-retain=168h    replica retention window ($TLD_REPLICA_RETAIN)
+  -replica-snapshot=24h   replica full snapshot interval ($TLD_REPLICA_SNAPSHOT)
+  -user-header=<name>     trust the username in this request header ($TLD_USER_HEADER)
+  -write-timeout=30s      response write timeout ($TLD_WRITE_TIMEOUT)
+  -h, --help              show this help and exit
+
+requests are made by the user named in the -user-header header, which must be
+set by an authenticating proxy that strips it from client requests. without
+-user-header, or a user by that name, requests are anonymous and routes that
+require a user respond 401.
+
+with -audit-retain, audit events older than the window are pruned every hour,
+as by tilde audit prune. with -purge-retain, users and orgs deleted longer ago
+than the window are purged every hour, as by tilde purge. both delete data
+for good and are off by default.`,
 	Flags: func(fs *flag.FlagSet, cfg any) {
 		if cfg, ok := cfg.(*core.Config); ok {
 			fs.StringVar(&cfg.ServeAddr, "addr", ":0", "")
+			fs.DurationVar(&cfg.AuditRetain, "audit-retain", 0, "")
 			fs.BoolVar(&cfg.ServeDev, "dev", false, "")
+			fs.StringVar(&cfg.ServeDevSocket, "dev-socket", "", "")
+			fs.DurationVar(&cfg.ServeShutdownGrace, "grace", 15*time.Second, "")
+			fs.DurationVar(&cfg.ServeIdleTimeout, "idle-timeout", 2*time.Minute, "")
+			fs.DurationVar(&cfg.PurgeRetain, "purge-retain", 0, "")
+			fs.DurationVar(&cfg.ServeReadTimeout, "read-timeout", 10*time.Second, "")
+			fs.StringVar(&cfg.ReplicaDir, "replica-dir", "", "")
+			fs.DurationVar(&cfg.ReplicaInterval, "replica-interval", time.Minute, "")
+			fs.DurationVar(&cfg.ReplicaRetain, "replica-retain", 7*24*time.Hour, "")
+			fs.DurationVar(&cfg.ReplicaSnapshot, "replica-snapshot", 24*time.Hour, "")
+			fs.StringVar(&cfg.ServeUserHeader, "user-header", "", "")
+			fs.DurationVar(&cfg.ServeWriteTimeout, "write-timeout", 30*time.Second, "")
 		}
 	},
 	Vars: map[string]string{
-		"addr": "TLD_ADDR",
+		"addr":             "TLD_ADDR",
+		"audit-retain":     "TLD_AUDIT_RETAIN",
+		"grace":            "TLD_SHUTDOWN_GRACE",
+		"idle-timeout":     "TLD_IDLE_TIMEOUT",
+		"purge-retain":     "TLD_PURGE_RETAIN",
+		"read-timeout":     "TLD_READ_TIMEOUT",
+		"replica-dir":      "TLD_REPLICA_DIR",
+		"replica-interval": "TLD_REPLICA_INTERVAL",
+		"replica-retain":   "TLD_REPLICA_RETAIN",
+		"replica-snapshot": "TLD_REPLICA_SNAPSHOT",
+		"user-header":      "TLD_USER_HEADER",
+		"write-timeout":    "TLD_WRITE_TIMEOUT",
 	},
 	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
 		cfg := target.(*core.Config)
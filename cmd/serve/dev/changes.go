@@ -0,0 +1,151 @@
+package dev
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+// metafile is the part of esbuild's metafile that tells which modules each
+// output bundles. Paths are relative to the working dir.
+type metafile struct {
+	Inputs map[string]struct {
+		Imports []metaImport `json:"imports"`
+	} `json:"inputs"`
+	Outputs map[string]struct {
+		Inputs map[string]struct{} `json:"inputs"`
+	} `json:"outputs"`
+}
+
+type metaImport struct {
+	Path     string `json:"path"`
+	External bool   `json:"external"`
+}
+
+// builds tracks the inputs and outputs of successive successful builds, to
+// tell clients what each one changed.
+type builds struct {
+	staticDir string
+	hmr       string // the absolute path of bundle.HMRModule
+
+	inputs  map[string][sha256.Size]byte
+	outputs map[string]string // esbuild's hashes, by public path
+}
+
+// changes compares a successful build with the last one. An updated output is
+// hot when each of its changed modules imports the HMR module, whose
+// components swap themselves in when the output is re-imported.
+func (b *builds) changes(result *esbuild.BuildResult) (ChangeEvent, error) {
+	change := ChangeEvent{
+		Type:    TypeChange,
+		Added:   []string{},
+		Removed: []string{},
+		Updated: []string{},
+		Modules: map[string][]string{},
+		Hot:     []string{},
+	}
+
+	outputs := make(map[string]string, len(result.OutputFiles))
+	for _, o := range result.OutputFiles {
+		p, err := b.publicPath(o.Path)
+		if err != nil {
+			return change, err
+		}
+		if strings.HasSuffix(p, ".map") {
+			continue
+		}
+		outputs[p] = o.Hash
+		if prev, ok := b.outputs[p]; !ok {
+			change.Added = append(change.Added, p)
+		} else if prev != o.Hash {
+			change.Updated = append(change.Updated, p)
+		}
+	}
+	for p := range b.outputs {
+		if _, ok := outputs[p]; !ok {
+			change.Removed = append(change.Removed, p)
+		}
+	}
+	slices.Sort(change.Added)
+	slices.Sort(change.Removed)
+	slices.Sort(change.Updated)
+
+	var meta metafile
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return change, err
+	}
+	inputs := make(map[string][sha256.Size]byte, len(meta.Inputs))
+	changed := map[string]bool{}
+	for in := range meta.Inputs {
+		data, err := os.ReadFile(in)
+		if err != nil {
+			// Inputs without files, like virtual modules, never change.
+			continue
+		}
+		inputs[in] = sha256.Sum256(data)
+		if prev, ok := b.inputs[in]; b.inputs != nil && (!ok || prev != inputs[in]) {
+			changed[in] = true
+		}
+	}
+
+	for out, o := range meta.Outputs {
+		p, err := b.publicPath(out)
+		if err != nil {
+			return change, err
+		}
+		if !slices.Contains(change.Updated, p) {
+			continue
+		}
+		var mods []string
+		for in := range o.Inputs {
+			if changed[in] {
+				mods = append(mods, in)
+			}
+		}
+		if len(mods) == 0 {
+			continue
+		}
+		slices.Sort(mods)
+		change.Modules[p] = mods
+		if !slices.ContainsFunc(mods, func(in string) bool {
+			return !b.acceptsHot(meta.Inputs[in].Imports)
+		}) {
+			change.Hot = append(change.Hot, p)
+		}
+	}
+	slices.Sort(change.Hot)
+
+	b.inputs, b.outputs = inputs, outputs
+	return change, nil
+}
+
+// acceptsHot reports whether imports include the HMR module.
+func (b *builds) acceptsHot(imports []metaImport) bool {
+	for _, imp := range imports {
+		if imp.External {
+			continue
+		}
+		if abs, err := filepath.Abs(imp.Path); err == nil && abs == b.hmr {
+			return true
+		}
+	}
+	return false
+}
+
+// publicPath returns the path the app serves an output at.
+func (b *builds) publicPath(out string) (string, error) {
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(b.staticDir, abs)
+	if err != nil {
+		return "", err
+	}
+	return "/public/" + filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,17 @@
+package dev
+
+import "time"
+
+// Exports for tests of the client hub.
+
+var NewHub = newHub
+
+// SetTimings replaces the timings of client connections, returning a func
+// restoring them.
+func SetTimings(write, pong, ping time.Duration, queue int) (restore func()) {
+	w, po, pi, q := writeWait, pongWait, pingPeriod, sendQueue
+	writeWait, pongWait, pingPeriod, sendQueue = write, pong, ping, queue
+	return func() {
+		writeWait, pongWait, pingPeriod, sendQueue = w, po, pi, q
+	}
+}
@@ -0,0 +1,252 @@
+package dev
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// These are vars so that tests can shorten them.
+var (
+	// writeWait bounds each write to a client.
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may go without answering a ping.
+	pongWait = 60 * time.Second
+	// pingPeriod is how often clients are pinged, within pongWait.
+	pingPeriod = pongWait * 9 / 10
+	// sendQueue is how many messages a client may fall behind by before it is
+	// dropped. A dropped browser reconnects and reloads, so it misses nothing.
+	sendQueue = 16
+)
+
+// client is a connected browser, written to by its own goroutine so that a
+// stalled tab holds up no other.
+type client struct {
+	conn      *websocket.Conn
+	send      chan []byte
+	addr      string
+	userAgent string
+	connected time.Time
+	sent      atomic.Int64
+}
+
+// ClientInfo describes a connected client on the status page.
+type ClientInfo struct {
+	Addr      string
+	UserAgent string
+	Connected time.Time
+	Sent      int64
+	Queued    int
+}
+
+// newHub returns a server without a builder, fanning messages out to clients
+// until Close.
+func newHub(logger *slog.Logger) *Server {
+	s := &Server{
+		logger:     logger,
+		done:       make(chan struct{}),
+		clients:    make(map[*client]bool),
+		register:   make(chan *client),
+		unregister: make(chan *client),
+		broadcast:  make(chan []byte),
+		statuses:   make(chan []byte),
+		inspect:    make(chan chan []ClientInfo),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run()
+	}()
+	return s
+}
+
+func (s *Server) run() {
+	defer func() {
+		for c := range s.clients {
+			close(c.send)
+		}
+	}()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case c := <-s.register:
+			s.logger.Info("dev client connected", "addr", c.addr)
+			s.clients[c] = true
+			if s.status != nil {
+				c.send <- s.status
+			}
+		case c := <-s.unregister:
+			if s.clients[c] {
+				s.logger.Info("dev client disconnected", "addr", c.addr)
+				delete(s.clients, c)
+				close(c.send)
+			}
+		case msg := <-s.broadcast:
+			s.fanOut(msg)
+		case msg := <-s.statuses:
+			s.status = msg
+			s.fanOut(msg)
+		case reply := <-s.inspect:
+			infos := make([]ClientInfo, 0, len(s.clients))
+			for c := range s.clients {
+				infos = append(infos, ClientInfo{
+					Addr:      c.addr,
+					UserAgent: c.userAgent,
+					Connected: c.connected,
+					Sent:      c.sent.Load(),
+					Queued:    len(c.send),
+				})
+			}
+			reply <- infos
+		}
+	}
+}
+
+// fanOut queues msg for every client, dropping those too far behind to take
+// it.
+func (s *Server) fanOut(msg []byte) {
+	s.logger.Debug("broadcasting", "clients", len(s.clients))
+	for c := range s.clients {
+		select {
+		case c.send <- msg:
+		default:
+			s.logger.Warn("dropping slow dev client", "addr", c.addr, "queued", len(c.send))
+			delete(s.clients, c)
+			close(c.send)
+		}
+	}
+}
+
+// errClosed is returned by Upgrade once the server is closed.
+var errClosed = errors.New("dev server closed")
+
+// join adds a goroutine to those Close waits for, unless the server is
+// already closed.
+func (s *Server) join() bool {
+	s.closing.Lock()
+	defer s.closing.Unlock()
+	select {
+	case <-s.done:
+		return false
+	default:
+		s.wg.Add(1)
+		return true
+	}
+}
+
+// Upgrade serves a client's websocket until it disconnects or the server is
+// closed. Clients only listen, so anything they send is discarded.
+func (s *Server) Upgrade(w http.ResponseWriter, r *http.Request) error {
+	if !s.join() {
+		http.Error(w, errClosed.Error(), http.StatusServiceUnavailable)
+		return errClosed
+	}
+	defer s.wg.Done()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+
+	c := &client{
+		conn:      conn,
+		send:      make(chan []byte, sendQueue),
+		addr:      r.RemoteAddr,
+		userAgent: r.UserAgent(),
+		connected: time.Now(),
+	}
+	select {
+	case s.register <- c:
+	case <-s.done:
+		return conn.Close()
+	}
+	// This handler's own count keeps wg above zero, so it may join here.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		c.write()
+	}()
+
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	select {
+	case s.unregister <- c:
+	case <-s.done:
+	}
+	return nil
+}
+
+// write sends queued messages and pings to c until its queue is closed or a
+// write fails, then closes the connection.
+func (c *client) write() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+			c.sent.Add(1)
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Broadcast queues msg for every connected client, or does nothing once the
+// server is closed.
+func (s *Server) Broadcast(msg []byte) {
+	select {
+	case s.broadcast <- msg:
+	case <-s.done:
+	}
+}
+
+// broadcastStatus queues msg for every connected client, and for clients as
+// they connect until the next status.
+func (s *Server) broadcastStatus(msg []byte) {
+	select {
+	case s.statuses <- msg:
+	case <-s.done:
+	}
+}
+
+// Clients describes the connected clients.
+func (s *Server) Clients() []ClientInfo {
+	reply := make(chan []ClientInfo, 1)
+	select {
+	case s.inspect <- reply:
+		return <-reply
+	case <-s.done:
+		return nil
+	}
+}
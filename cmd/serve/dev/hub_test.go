@@ -0,0 +1,205 @@
+package dev_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jonathonwebb/tilde/cmd/serve/dev"
+)
+
+// startHub serves a hub with the given timings, returning it and a func
+// connecting clients to it.
+func startHub(t *testing.T, write, pong, ping time.Duration, queue int) (*dev.Server, func() *websocket.Conn) {
+	t.Helper()
+	t.Cleanup(dev.SetTimings(write, pong, ping, queue))
+
+	s := dev.NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = s.Upgrade(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() {
+		if err := s.Close(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	dial := func() *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+	return s, dial
+}
+
+// waitClients waits for the hub to have n clients.
+func waitClients(t *testing.T, s *dev.Server, n int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for len(s.Clients()) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %d clients, but got %d", n, len(s.Clients()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// receive reads messages from conn in the background, answering pings, until
+// it fails.
+func receive(conn *websocket.Conn) <-chan []byte {
+	msgs := make(chan []byte)
+	go func() {
+		defer close(msgs)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- msg
+		}
+	}()
+	return msgs
+}
+
+func TestHubDropsSlowClient(t *testing.T) {
+	const queue = 2
+	s, dial := startHub(t, 10*time.Second, time.Minute, time.Minute, queue)
+	fast := receive(dial())
+	dial() // never reads
+	waitClients(t, s, 2, 5*time.Second)
+
+	// Once the socket buffers of the client that never reads fill up, its
+	// queue does too, and it is dropped.
+	msg := bytes.Repeat([]byte("x"), 1<<20)
+	sent := 0
+	for len(s.Clients()) == 2 {
+		if sent == 256 {
+			t.Fatalf("want the slow client dropped, but it took %d messages", sent)
+		}
+		s.Broadcast(msg)
+		sent++
+		select {
+		case got, ok := <-fast:
+			if !ok || !bytes.Equal(got, msg) {
+				t.Fatalf("want the fast client to receive message %d", sent)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("fast client didn't receive message %d", sent)
+		}
+	}
+	if sent <= queue {
+		t.Errorf("want the slow client dropped after more than %d messages, but got %d", queue, sent)
+	}
+
+	s.Broadcast([]byte("after"))
+	select {
+	case got := <-fast:
+		if string(got) != "after" {
+			t.Errorf("want the fast client to keep receiving broadcasts, but got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("fast client didn't receive a broadcast after the slow one was dropped")
+	}
+}
+
+func TestHubPongDeadline(t *testing.T) {
+	s, dial := startHub(t, time.Second, 200*time.Millisecond, 50*time.Millisecond, 16)
+	live := receive(dial())
+	dial() // never reads, so never answers pings
+	waitClients(t, s, 2, 5*time.Second)
+
+	waitClients(t, s, 1, 5*time.Second)
+	time.Sleep(400 * time.Millisecond)
+	if got := len(s.Clients()); got != 1 {
+		t.Errorf("want the client answering pings kept, but got %d clients", got)
+	}
+	s.Broadcast([]byte("hi"))
+	select {
+	case got := <-live:
+		if string(got) != "hi" {
+			t.Errorf("want hi, but got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the client answering pings didn't receive a broadcast")
+	}
+}
+
+func TestHubWriteDeadline(t *testing.T) {
+	s, dial := startHub(t, 200*time.Millisecond, time.Minute, time.Minute, 16)
+	dial() // never reads, so writes to it stall
+	waitClients(t, s, 1, 5*time.Second)
+
+	// Fewer messages than the queue holds, so only the write deadline can
+	// drop the client.
+	msg := bytes.Repeat([]byte("x"), 8<<20)
+	for range 8 {
+		s.Broadcast(msg)
+	}
+	waitClients(t, s, 0, 5*time.Second)
+}
+
+func TestHubCloseWaitsForClients(t *testing.T) {
+	t.Cleanup(dev.SetTimings(time.Second, time.Minute, time.Minute, 16))
+
+	s := dev.NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var serving atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serving.Add(1)
+		defer serving.Add(-1)
+		_ = s.Upgrade(w, r)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	var conns []*websocket.Conn
+	for range 3 {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	waitClients(t, s, 3, 5*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Upgrade returns just before the handler's deferred decrement.
+	deadline := time.Now().Add(time.Second)
+	for serving.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("want no clients served after Close, but got %d", serving.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, conn := range conns {
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			t.Errorf("want client %d sent a going away close, but got %v", i, err)
+		}
+	}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("want dialing a closed server to fail")
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want status %d, but got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
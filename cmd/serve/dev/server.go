@@ -1,178 +1,305 @@
+// Package dev runs esbuild in watch mode for tilde serve -dev, and tells
+// connected browsers over a websocket when its outputs change.
 package dev
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	esbuild "github.com/evanw/esbuild/pkg/api"
 	"github.com/gorilla/websocket"
-	"github.com/r3labs/sse/v2"
-	"golang.org/x/sync/errgroup"
+	"github.com/jonathonwebb/tilde/internal/bundle"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	Subprotocols:    []string{"ws"},
 	CheckOrigin:     func(r *http.Request) bool { return true },
 }
 
+type Options struct {
+	// AssetsDir holds the entrypoints to build, as for tilde assets.
+	AssetsDir string
+	// StaticDir is the public dir, whose build dir esbuild serves.
+	StaticDir string
+	Logger    *slog.Logger
+}
+
 type Server struct {
+	// Addr and Port are where esbuild serves the public dir, with its build
+	// outputs held in memory.
 	Addr string
 	Port int
 
 	logger     *slog.Logger
-	clients    map[*websocket.Conn]bool
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
+	builder    esbuild.BuildContext
+	builds     builds // only used by reportBuild, which esbuild calls a build at a time
+	wg         sync.WaitGroup
+	closing    sync.Mutex // held to close done, so nothing joins wg once Close waits on it
+	done       chan struct{}
+	clients    map[*client]bool
+	register   chan *client
+	unregister chan *client
 	broadcast  chan []byte
+	statuses   chan []byte
+	inspect    chan chan []ClientInfo
+	status     []byte // the latest build event, sent to clients as they connect
+
+	mu     sync.Mutex
+	events []event // the latest events, for the status page
+}
+
+// Types of the messages sent to clients.
+const (
+	TypeChange = "change"
+	TypeBuild  = "build"
+	TypeReload = "reload"
+)
+
+// ReloadEvent asks clients to reload the page, as when the app server has
+// restarted or its templates have changed.
+type ReloadEvent struct {
+	Type string `json:"type"`
 }
 
+// ChangeEvent lists the public paths of outputs changed by a successful build.
+// Modules lists, by public path, the modules whose changes each updated output
+// holds, and Hot the updated outputs whose changes can be swapped into a page
+// by re-importing them rather than reloading it.
 type ChangeEvent struct {
-	Added   []string `json:"added"`
-	Removed []string `json:"removed"`
-	Updated []string `json:"updated"`
-}
-
-func NewServer(logger *slog.Logger) (*Server, error) {
-	s := Server{
-		logger:     logger,
-		clients:    make(map[*websocket.Conn]bool),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
-		broadcast:  make(chan []byte),
-	}
-	go s.run()
-
-	builder, builderErr := esbuild.Context(esbuild.BuildOptions{
-		EntryPoints: []string{
-			"ui/assets/entrypoints/**/*.js",
-			"ui/assets/entrypoints/**/*.jsx",
-			"ui/assets/entrypoints/**/*.ts",
-			"ui/assets/entrypoints/**/*.tsx",
-			"ui/assets/entrypoints/**/*.css",
+	Type    string              `json:"type"`
+	Added   []string            `json:"added"`
+	Removed []string            `json:"removed"`
+	Updated []string            `json:"updated"`
+	Modules map[string][]string `json:"modules"`
+	Hot     []string            `json:"hot"`
+}
+
+// BuildEvent reports the errors and warnings of each build, succeeded or not.
+type BuildEvent struct {
+	Type     string         `json:"type"`
+	Errors   []BuildMessage `json:"errors"`
+	Warnings []BuildMessage `json:"warnings"`
+}
+
+// BuildMessage is an esbuild error or warning, with the source line it points
+// at if it has one. Line is 1-based, and Column and Length are in bytes from
+// the start of the line, as esbuild reports them.
+type BuildMessage struct {
+	Text    string `json:"text"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Length  int    `json:"length"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// NewServer starts esbuild watching and serving opts.AssetsDir, and sending
+// the outcome of each build and the outputs it changed to websocket clients
+// until Close.
+func NewServer(opts Options) (_ *Server, err error) {
+	staticDir, err := filepath.Abs(opts.StaticDir)
+	if err != nil {
+		return nil, err
+	}
+	hmr, err := filepath.Abs(filepath.Join(opts.AssetsDir, bundle.HMRModule))
+	if err != nil {
+		return nil, err
+	}
+
+	// Builds report to clients as soon as esbuild starts watching.
+	s := newHub(opts.Logger)
+	s.builds = builds{staticDir: staticDir, hmr: hmr}
+	defer func() {
+		if err != nil {
+			close(s.done)
+			s.wg.Wait()
+		}
+	}()
+
+	buildOpts := bundle.DevOptions(opts.AssetsDir, opts.StaticDir)
+	buildOpts.Plugins = append(buildOpts.Plugins, esbuild.Plugin{
+		Name: "tilde-dev",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnEnd(func(result *esbuild.BuildResult) (esbuild.OnEndResult, error) {
+				s.reportBuild(result)
+				return esbuild.OnEndResult{}, nil
+			})
 		},
-		Sourcemap:   esbuild.SourceMapInline,
-		Bundle:      true,
-		Outdir:      "ui/static/build",
-		Format:      esbuild.FormatESModule,
-		JSX:         esbuild.JSXTransform,
-		JSXFactory:  "h",
-		JSXFragment: "Fragment",
 	})
-	if builderErr != nil {
-		logger.Error(builderErr.Error())
-		// TODO: handle error
+	builder, ctxErr := esbuild.Context(buildOpts)
+	if ctxErr != nil {
+		return nil, fmt.Errorf("esbuild: %w", ctxErr)
 	}
+	s.builder = builder
 
-	watchErr := builder.Watch(esbuild.WatchOptions{})
-	if watchErr != nil {
-		logger.Error(watchErr.Error())
-		// TODO: handle error
+	if err := builder.Watch(esbuild.WatchOptions{}); err != nil {
+		builder.Dispose()
+		return nil, fmt.Errorf("esbuild watch: %w", err)
 	}
 
-	serveResult, serveErr := builder.Serve(esbuild.ServeOptions{
-		Servedir: "ui/static",
+	serveResult, err := builder.Serve(esbuild.ServeOptions{
+		Host:     "127.0.0.1",
+		Servedir: opts.StaticDir,
 		CORS:     esbuild.CORSOptions{Origin: []string{"*"}},
 	})
-	if serveErr != nil {
-		logger.Error(serveErr.Error())
-		// TODO: handle error
+	if err != nil {
+		builder.Dispose()
+		return nil, fmt.Errorf("esbuild serve: %w", err)
+	}
+	if len(serveResult.Hosts) == 0 {
+		builder.Dispose()
+		return nil, errors.New("esbuild serve: no listening hosts")
 	}
-	// TODO: need to account for multiple hosts, or len 0?
 	s.Addr = serveResult.Hosts[0]
 	s.Port = int(serveResult.Port)
-	logger.Info(fmt.Sprintf("started esbuild server @ %s:%d", serveResult.Hosts[0], serveResult.Port))
-
-	sseClient := sse.NewClient(fmt.Sprintf("http://%s:%d/esbuild", serveResult.Hosts[0], serveResult.Port))
+	s.logger.Info("started esbuild server", "addr", s.esbuildAddr())
+	return s, nil
+}
 
-	processPaths := func(paths []string, result *[]string) {
-		for _, path := range paths {
-			if !strings.HasSuffix(path, ".map") {
-				*result = append(*result, path)
-			}
+// Handle mounts the websocket clients connect to at /_dev/ws and a status page
+// at /_dev/status, and proxies /public/build/ to esbuild's unhashed outputs.
+// Hidden files, like the asset manifest, aren't proxied.
+func (s *Server) Handle(m *http.ServeMux) {
+	esbuild := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: s.esbuildAddr()})
+	m.Handle("GET /public/build/", http.StripPrefix("/public", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/.") {
+			http.NotFound(w, r)
+			return
 		}
+		esbuild.ServeHTTP(w, r)
+	})))
+	m.HandleFunc("GET /_dev/ws", func(w http.ResponseWriter, r *http.Request) {
+		_ = s.Upgrade(w, r)
+	})
+	m.HandleFunc("GET /_dev/status", s.serveStatus)
+}
+
+// SocketURL returns the URL of the websocket mounted by Handle on a listener
+// at addr, as a browser on the same machine reaches it.
+func SocketURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
 	}
+	if net.ParseIP(host).IsUnspecified() {
+		host = "localhost"
+	}
+	return (&url.URL{Scheme: "ws", Host: net.JoinHostPort(host, port), Path: "/_dev/ws"}).String()
+}
 
-	g := new(errgroup.Group)
-	g.Go(func() error {
-		return sseClient.Subscribe("change", func(msg *sse.Event) {
-			var ce ChangeEvent
-			added := []string{}
-			removed := []string{}
-			updated := []string{}
-
-			if len(msg.Data) > 0 {
-				err := json.Unmarshal(msg.Data, &ce)
-				if err == nil {
-					processPaths(ce.Added, &added)
-					processPaths(ce.Removed, &removed)
-					processPaths(ce.Updated, &updated)
-					newCe, err := json.Marshal(ChangeEvent{Added: added, Removed: removed, Updated: updated})
-					if err == nil {
-						s.Broadcast(newCe)
-					}
-				}
-			}
-		})
-	})
-	if err := g.Wait(); err != nil {
-		return nil, err
+func (s *Server) esbuildAddr() string {
+	return net.JoinHostPort(s.Addr, strconv.Itoa(s.Port))
+}
+
+// reportBuild logs the outcome of a build and sends it to clients.
+func (s *Server) reportBuild(result *esbuild.BuildResult) {
+	for _, m := range result.Errors {
+		s.logger.Error("build error", "msg", formatMessage(m))
+	}
+	for _, m := range result.Warnings {
+		s.logger.Warn("build warning", "msg", formatMessage(m))
 	}
 
-	return &s, nil
-}
-
-func (s *Server) run() {
-	for {
-		select {
-		case client := <-s.register:
-			s.logger.Info("dev client connected")
-			s.clients[client] = true
-		case client := <-s.unregister:
-			s.logger.Info("dev client disconnected")
-			delete(s.clients, client)
-		case msg := <-s.broadcast:
-			s.logger.Info("broadcasting")
-			for client := range s.clients {
-				err := client.WriteMessage(websocket.TextMessage, msg)
-				if err != nil {
-					s.logger.Error(err.Error())
-					_ = client.Close()
-					delete(s.clients, client)
-				}
-			}
-		}
+	build := BuildEvent{
+		Type:     TypeBuild,
+		Errors:   buildMessages(result.Errors),
+		Warnings: buildMessages(result.Warnings),
 	}
-}
+	b, err := json.Marshal(build)
+	if err != nil {
+		s.logger.Error("encode build event", "err", err)
+		return
+	}
+	s.record(event{Type: TypeBuild, Errors: build.Errors, Warnings: build.Warnings})
+	s.broadcastStatus(b)
 
-func (s *Server) Upgrade(w http.ResponseWriter, r *http.Request) error {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if len(result.Errors) > 0 {
+		return
+	}
+	change, err := s.builds.changes(result)
 	if err != nil {
-		s.logger.Error(err.Error())
-		return err
+		s.logger.Error("compare build outputs", "err", err)
+		return
+	}
+	paths := slices.Concat(change.Added, change.Removed, change.Updated)
+	if len(paths) == 0 {
+		return
 	}
+	if b, err = json.Marshal(change); err != nil {
+		s.logger.Error("encode change event", "err", err)
+		return
+	}
+	s.record(event{Type: TypeChange, Paths: paths})
+	s.Broadcast(b)
+}
 
-	defer func() {
-		s.unregister <- conn
-		if err := conn.Close(); err != nil {
-			s.logger.Error(fmt.Sprintf("error closing connection: %v", err))
+func buildMessages(msgs []esbuild.Message) []BuildMessage {
+	bms := make([]BuildMessage, 0, len(msgs))
+	for _, m := range msgs {
+		bm := BuildMessage{Text: m.Text}
+		if l := m.Location; l != nil {
+			bm.File = l.File
+			bm.Line = l.Line
+			bm.Column = l.Column
+			bm.Length = l.Length
+			bm.Snippet = l.LineText
 		}
-	}()
+		bms = append(bms, bm)
+	}
+	return bms
+}
 
-	s.register <- conn
+func formatMessage(m esbuild.Message) string {
+	if l := m.Location; l != nil {
+		return fmt.Sprintf("%s:%d:%d: %s", l.File, l.Line, l.Column, m.Text)
+	}
+	return m.Text
+}
 
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
+// Reload asks every connected client to reload the page.
+func (s *Server) Reload() {
+	b, err := json.Marshal(ReloadEvent{Type: TypeReload})
+	if err != nil {
+		s.logger.Error("encode reload event", "err", err)
+		return
 	}
-	return nil
+	s.record(event{Type: TypeReload})
+	s.Broadcast(b)
 }
 
-func (s *Server) Broadcast(msg []byte) {
-	s.broadcast <- msg
+// Close stops esbuild and disconnects every client, waiting for the server's
+// goroutines, including those serving clients, to finish until ctx is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.closing.Lock()
+	close(s.done)
+	s.closing.Unlock()
+	if s.builder != nil {
+		s.builder.Dispose()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
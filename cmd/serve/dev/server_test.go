@@ -0,0 +1,173 @@
+package dev_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+	"github.com/jonathonwebb/tilde/cmd/serve/dev"
+)
+
+func writeFiles(t testing.TB, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readChange reads messages from conn until a change event.
+func readChange(t testing.TB, conn *websocket.Conn) dev.ChangeEvent {
+	t.Helper()
+	for {
+		var msg json.RawMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		var change dev.ChangeEvent
+		if err := json.Unmarshal(msg, &change); err != nil {
+			t.Fatal(err)
+		}
+		if change.Type == dev.TypeChange {
+			return change
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	// esbuild resolves packages and reports files relative to the working dir.
+	t.Chdir(t.TempDir())
+	writeFiles(t, map[string]string{
+		"node_modules/preact/package.json": `{"name": "preact", "exports": {".": "./preact.js", "./hooks": "./hooks.js"}}`,
+		"node_modules/preact/preact.js":    "export const h = () => {};\n",
+		"node_modules/preact/hooks.js":     "export const useState = () => {};\n",
+		"assets/hmr.ts":                    "export const hot = (c: unknown) => c;\n",
+		"assets/App.tsx":                   "import { hot } from \"./hmr\";\nexport default hot(() => 1);\n",
+		"assets/entrypoints/main.ts":       "export const x = (;\n",
+		"assets/entrypoints/app.tsx":       "import App from \"../App\";\nApp();\n",
+		"assets/entrypoints/main.css":      "p { color: red; }\n",
+	})
+
+	s, err := dev.NewServer(dev.Options{
+		AssetsDir: "assets",
+		StaticDir: "static",
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	m := http.NewServeMux()
+	s.Handle(m)
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/_dev/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	// A client connecting after a build learns how it went.
+	var build dev.BuildEvent
+	if err := conn.ReadJSON(&build); err != nil {
+		t.Fatal(err)
+	}
+	want := dev.BuildEvent{
+		Type: dev.TypeBuild,
+		Errors: []dev.BuildMessage{{
+			Text:    `Unexpected ";"`,
+			File:    "assets/entrypoints/main.ts",
+			Line:    1,
+			Column:  18,
+			Length:  1,
+			Snippet: "export const x = (;",
+		}},
+		Warnings: []dev.BuildMessage{},
+	}
+	if diff := cmp.Diff(want, build); diff != "" {
+		t.Errorf("build event mismatch (-want +got):\n%s", diff)
+	}
+
+	s.Reload()
+	var msg json.RawMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(`{"type":"reload"}`, string(msg)); diff != "" {
+		t.Errorf("reload event mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := len(s.Clients()); got != 1 {
+		t.Errorf("want 1 client, but got %d", got)
+	}
+	resp, err := http.Get(srv.URL + "/_dev/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"clients (1)", "failed with 1 error(s)", "reload"} {
+		if !strings.Contains(string(body), s) {
+			t.Errorf("want status page to contain %q, but got:\n%s", s, body)
+		}
+	}
+
+	// Changes to modules importing the HMR module can be swapped in place, and
+	// others can't.
+	writeFiles(t, map[string]string{"assets/entrypoints/main.ts": "export const x = 1;\n"})
+	readChange(t, conn)
+	for _, tt := range []struct {
+		file string
+		want dev.ChangeEvent
+	}{
+		{"assets/App.tsx", dev.ChangeEvent{
+			Type:    dev.TypeChange,
+			Added:   []string{},
+			Removed: []string{},
+			Updated: []string{"/public/build/app.js"},
+			Modules: map[string][]string{"/public/build/app.js": {"assets/App.tsx"}},
+			Hot:     []string{"/public/build/app.js"},
+		}},
+		{"assets/entrypoints/main.ts", dev.ChangeEvent{
+			Type:    dev.TypeChange,
+			Added:   []string{},
+			Removed: []string{},
+			Updated: []string{"/public/build/main.js"},
+			Modules: map[string][]string{"/public/build/main.js": {"assets/entrypoints/main.ts"}},
+			Hot:     []string{},
+		}},
+	} {
+		b, err := os.ReadFile(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, map[string]string{tt.file: string(b) + "// changed\n"})
+		if diff := cmp.Diff(tt.want, readChange(t, conn)); diff != "" {
+			t.Errorf("change to %s mismatch (-want +got):\n%s", tt.file, diff)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package dev
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// maxEvents is how many of the latest events the status page lists.
+const maxEvents = 50
+
+// event is a message sent to clients, as the status page lists it.
+type event struct {
+	Time     time.Time
+	Type     string
+	Paths    []string
+	Errors   []BuildMessage
+	Warnings []BuildMessage
+}
+
+func (s *Server) record(e event) {
+	e.Time = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	if len(s.events) > maxEvents {
+		s.events = slices.Delete(s.events, 0, len(s.events)-maxEvents)
+	}
+}
+
+var statusPage = template.Must(template.New("status").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String() + " ago"
+	},
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta http-equiv="refresh" content="5">
+  <title>tilde dev status</title>
+  <style>
+    body { font: 14px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; margin: 2rem; }
+    table { border-collapse: collapse; margin-bottom: 2rem; }
+    th, td { text-align: left; vertical-align: top; padding: 0.25rem 1rem 0.25rem 0; }
+    .error { color: #c0392b; }
+    .warning { color: #b7950b; }
+    ul { margin: 0; padding-left: 1rem; }
+  </style>
+</head>
+<body>
+  <h1>tilde dev</h1>
+  <p>esbuild serving at {{.Esbuild}}, refreshed every 5s.</p>
+
+  <h2>clients ({{len .Clients}})</h2>
+  {{if .Clients}}
+  <table>
+    <tr><th>addr</th><th>connected</th><th>sent</th><th>queued</th><th>user agent</th></tr>
+    {{range .Clients}}
+    <tr><td>{{.Addr}}</td><td>{{ago .Connected}}</td><td>{{.Sent}}</td><td>{{.Queued}}</td><td>{{.UserAgent}}</td></tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>no clients connected.</p>
+  {{end}}
+
+  <h2>events</h2>
+  {{if .Events}}
+  <table>
+    <tr><th>time</th><th>type</th><th>details</th></tr>
+    {{range .Events}}
+    <tr>
+      <td>{{.Time.Format "15:04:05.000"}}</td>
+      <td>{{.Type}}</td>
+      <td>
+        {{if eq .Type "build"}}
+          {{if .Errors}}<span class="error">failed with {{len .Errors}} error(s)</span>{{else}}succeeded{{end}}{{if .Warnings}}, <span class="warning">{{len .Warnings}} warning(s)</span>{{end}}
+          <ul>
+            {{range .Errors}}<li class="error">{{if .File}}{{.File}}:{{.Line}}:{{.Column}}: {{end}}{{.Text}}</li>{{end}}
+            {{range .Warnings}}<li class="warning">{{if .File}}{{.File}}:{{.Line}}:{{.Column}}: {{end}}{{.Text}}</li>{{end}}
+          </ul>
+        {{else if .Paths}}
+          <ul>{{range .Paths}}<li>{{.}}</li>{{end}}</ul>
+        {{end}}
+      </td>
+    </tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>no events yet.</p>
+  {{end}}
+</body>
+</html>
+`))
+
+// serveStatus shows the connected clients and the latest events, newest first.
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	clients := s.Clients()
+	slices.SortFunc(clients, func(a, b ClientInfo) int {
+		return a.Connected.Compare(b.Connected)
+	})
+	s.mu.Lock()
+	events := slices.Clone(s.events)
+	s.mu.Unlock()
+	slices.Reverse(events)
+
+	var buf bytes.Buffer
+	err := statusPage.Execute(&buf, struct {
+		Esbuild string
+		Clients []ClientInfo
+		Events  []event
+	}{s.esbuildAddr(), clients, events})
+	if err != nil {
+		s.logger.Error("render status page", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = buf.WriteTo(w)
+}
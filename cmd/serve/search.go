@@ -0,0 +1,66 @@
+package serve
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/jonathonwebb/tilde/internal/authz"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type searchResult struct {
+	Kind    store.Kind `json:"kind"`
+	Id      int64      `json:"id"`
+	Name    string     `json:"name"`
+	Snippet string     `json:"snippet"`
+	Rank    float64    `json:"rank"`
+}
+
+// search serves GET /search?q=<query>[&kind=<kind>...] as JSON, finding the
+// users and the orgs the subject is a member of. Snippets are HTML with matches
+// wrapped in <mark>.
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		http.Error(w, "missing query parameter q", http.StatusBadRequest)
+		return
+	}
+	var kinds []store.Kind
+	for _, s := range query["kind"] {
+		kind, err := store.ParseKind(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		kinds = append(kinds, kind)
+	}
+
+	sub := authz.SubjectFrom(r.Context())
+	results, err := store.NewSearchStore(app.db).As(sub.UserId).Search(r.Context(), q, kinds...)
+	if err != nil {
+		app.log.Error("search", "query", q, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Results []searchResult `json:"results"`
+	}{Results: make([]searchResult, len(results))}
+	for i, res := range results {
+		res.Snippet = html.EscapeString(res.Snippet)
+		resp.Results[i] = searchResult{
+			Kind:    res.Kind,
+			Id:      res.Id,
+			Name:    res.Name,
+			Snippet: res.Highlight("<mark>", "</mark>"),
+			Rank:    res.Rank,
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		app.log.Error("write search results", "err", err)
+	}
+}
@@ -0,0 +1,43 @@
+package serve
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jonathonwebb/tilde/internal/authz"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+// subject puts the user named by the -user-header request header into the
+// request context as the subject of authz checks and the actor of audited
+// changes. Requests without the header, or naming no user, are anonymous.
+func (app *application) subject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.cfg.ServeUserHeader == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := r.Header.Get(app.cfg.ServeUserHeader)
+		if name == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		u, err := app.users.GetByName(r.Context(), name)
+		if errors.Is(err, store.ErrNotFound) {
+			app.log.Debug("unknown user, serving as anonymous", "user", name)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			app.log.Error("resolve subject", "user", name, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		ctx := authz.WithSubject(r.Context(), authz.Subject{UserId: u.Id})
+		ctx = core.WithActor(ctx, fmt.Sprintf("user:%d", u.Id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,56 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/models"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type application struct {
+	log   *slog.Logger
+	users *store.UserStore
+}
+
+// run opens the database and calls fn with the user store, logging any error.
+func run(ctx context.Context, e *cli.Env, cfg *core.Config, fn func(context.Context, *application) error) (err error) {
+	log := cfg.NewLogger(e.Stderr, "users")
+	defer func() {
+		if err != nil {
+			log.Error(err.Error())
+		}
+	}()
+
+	ctx = core.WithActor(ctx, e.Actor())
+
+	log.Debug("connecting to db", "path", cfg.DbConnString)
+	db, err := cfg.OpenDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db.Reader(), log), Log: log, Sources: migrations.All}
+	if err := m.CheckLatest(ctx); err != nil {
+		return err
+	}
+
+	return fn(ctx, &application{log: log, users: store.NewUserStore(db)})
+}
+
+func writeUsers(w io.Writer, format cli.OutputFormat, users ...models.User) error {
+	rows := make([][]any, len(users))
+	for i, u := range users {
+		rows[i] = []any{u.Id, u.Username}
+	}
+	return cli.WriteRows(w, format, []string{"id", "username"}, rows)
+}
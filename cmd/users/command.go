@@ -0,0 +1,33 @@
+package users
+
+import (
+	"github.com/jonathonwebb/tilde/internal/cli"
+)
+
+var Cmd = cli.Command{
+	Name:  "users",
+	Usage: "usage: tilde [root flags] users <command>",
+	Help: `usage: tilde [root flags] users <command>
+
+manage user accounts. commands refuse to run unless the database schema is at
+the latest migration.
+
+commands:
+  create      create a user
+  delete      delete a user
+  list        list users
+  rename      rename a user
+  restore     restore a deleted user
+  show        show a user
+
+flags:
+  -h, -help   show this help and exit`,
+	Commands: []*cli.Command{
+		&createCmd,
+		&deleteCmd,
+		&listCmd,
+		&renameCmd,
+		&restoreCmd,
+		&showCmd,
+	},
+}
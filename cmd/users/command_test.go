@@ -0,0 +1,122 @@
+package users_test
+
+import (
+	"database/sql"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	migratecmd "github.com/jonathonwebb/tilde/cmd/migrate"
+	"github.com/jonathonwebb/tilde/cmd/users"
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+func TestUsersCommand(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+
+	t.Run("before migrating", func(t *testing.T) {
+		e, cfg, errBuf, _ := setUp(t, dbPath, "create", "alice")
+
+		if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitFailure {
+			t.Errorf("want exit status = %v, but got %v", cli.ExitFailure, got)
+		}
+		if !strings.Contains(errBuf.String(), "run tilde migrate") {
+			t.Errorf("want schema version error, but got %q", errBuf.String())
+		}
+	})
+
+	migrate(t, dbPath)
+
+	for _, name := range []string{"alice", "bob"} {
+		e, cfg, errBuf, _ := setUp(t, dbPath, "create", name)
+		if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+		}
+	}
+
+	t.Run("list", func(t *testing.T) {
+		e, cfg, errBuf, outBuf := setUp(t, dbPath, "list", "-format=json", "-after=1")
+
+		if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+			t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+		}
+
+		want := `[
+  {"id": 2, "username": "bob"}
+]
+`
+		if diff := cmp.Diff(want, outBuf.String()); diff != "" {
+			t.Errorf("out output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("rename to taken name", func(t *testing.T) {
+		e, cfg, errBuf, _ := setUp(t, dbPath, "rename", "bob", "alice")
+
+		if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitFailure {
+			t.Errorf("want exit status = %v, but got %v", cli.ExitFailure, got)
+		}
+		if !strings.Contains(errBuf.String(), "already exists") {
+			t.Errorf("want conflict error, but got %q", errBuf.String())
+		}
+	})
+}
+
+func TestUsersCommandAfterSchemaLoad(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+
+	e, cfg, errBuf, _ := setUp(t, dbPath, "-to=schema")
+	e.Args[0] = "migrate"
+	if got := migratecmd.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+		t.Fatalf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+	}
+
+	e, cfg, errBuf, _ = setUp(t, dbPath, "create", "alice")
+	if got := users.Cmd.Execute(t.Context(), e, cfg); got != cli.ExitSuccess {
+		t.Errorf("want exit status = %v, but got %v: %s", cli.ExitSuccess, got, errBuf)
+	}
+}
+
+func migrate(t testing.TB, dbPath string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(slog.DiscardHandler)
+	m := &schema.Migrator{Store: schema.NewSqlite3SchemaStore(db, log), Log: log, Sources: migrations.All}
+	if err := m.ApplyLatest(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setUp(t testing.TB, dbPath string, args ...string) (*cli.Env, *core.Config, *strings.Builder, *strings.Builder) {
+	t.Helper()
+
+	var errBuf, outBuf strings.Builder
+
+	return &cli.Env{
+			Log:    log.New(&errBuf, "", 0),
+			Stderr: &errBuf,
+			Stdout: &outBuf,
+			Args:   append([]string{"users"}, args...),
+		}, &core.Config{
+			Env:           "test",
+			Level:         slog.LevelError,
+			Format:        core.JSONFormat,
+			DbConnString:  dbPath,
+			DbForeignKeys: true,
+		},
+		&errBuf,
+		&outBuf
+}
@@ -0,0 +1,243 @@
+package users
+
+import (
+	"context"
+	"flag"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+const (
+	createUsage = "usage: tilde [root flags] users create [-h] [flags] <username>"
+	createHelp  = `usage: tilde [root flags] users create [-h] [flags] <username>
+
+create a user named <username>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	deleteUsage = "usage: tilde [root flags] users delete [-h] <username>"
+	deleteHelp  = `usage: tilde [root flags] users delete [-h] <username>
+
+delete <username>, hiding them and their org memberships until they are
+restored or purged. a user who is the last owner of an org cannot be deleted.
+
+flags:
+  -h, -help   show this help and exit`
+
+	listUsage = "usage: tilde [root flags] users list [-h] [flags]"
+	listHelp  = `usage: tilde [root flags] users list [-h] [flags]
+
+list users ordered by id. when more users remain, the -after value for the
+next page is logged.
+
+flags:
+  -after=0        list users with ids greater than this
+  -format=table   output format (table|json|csv)
+  -limit=100      maximum number of users to list
+  -h, -help       show this help and exit`
+
+	renameUsage = "usage: tilde [root flags] users rename [-h] [flags] <username> <new-username>"
+	renameHelp  = `usage: tilde [root flags] users rename [-h] [flags] <username> <new-username>
+
+rename <username> to <new-username>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	restoreUsage = "usage: tilde [root flags] users restore [-h] [flags] <username>"
+	restoreHelp  = `usage: tilde [root flags] users restore [-h] [flags] <username>
+
+restore the deleted user named <username>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+
+	showUsage = "usage: tilde [root flags] users show [-h] [flags] <username>"
+	showHelp  = `usage: tilde [root flags] users show [-h] [flags] <username>
+
+show the user named <username>.
+
+flags:
+  -format=table   output format (table|json|csv)
+  -h, -help       show this help and exit`
+)
+
+func formatFlag(fs *flag.FlagSet, target any) {
+	cfg := target.(*core.Config)
+	fs.TextVar(&cfg.Output, "format", &cli.TableOutput, "")
+}
+
+var createCmd = cli.Command{
+	Name:  "create",
+	Usage: createUsage,
+	Help:  createHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(createUsage, "expected <username> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			u, err := app.users.Create(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			return writeUsers(e.Stdout, cfg.Output, *u)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var deleteCmd = cli.Command{
+	Name:  "delete",
+	Usage: deleteUsage,
+	Help:  deleteHelp,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(deleteUsage, "expected <username> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			u, err := app.users.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			return app.users.Delete(ctx, u.Id)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var listCmd = cli.Command{
+	Name:  "list",
+	Usage: listUsage,
+	Help:  listHelp,
+	Flags: func(fs *flag.FlagSet, target any) {
+		cfg := target.(*core.Config)
+		formatFlag(fs, target)
+		fs.Int64Var(&cfg.ListAfter, "after", 0, "")
+		fs.IntVar(&cfg.ListLimit, "limit", store.DefaultLimit, "")
+	},
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 0 {
+			e.PrintUsageErr(listUsage, "expected 0 args, but got %d", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			page, err := app.users.List(ctx, cfg.ListAfter, cfg.ListLimit)
+			if err != nil {
+				return err
+			}
+			if page.Next != 0 {
+				app.log.Info("more users", "after", page.Next)
+			}
+			return writeUsers(e.Stdout, cfg.Output, page.Items...)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var renameCmd = cli.Command{
+	Name:  "rename",
+	Usage: renameUsage,
+	Help:  renameHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 2 {
+			e.PrintUsageErr(renameUsage, "expected <username> and <new-username> args, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			u, err := app.users.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			if u, err = app.users.Rename(ctx, u.Id, e.Args[1]); err != nil {
+				return err
+			}
+			return writeUsers(e.Stdout, cfg.Output, *u)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var showCmd = cli.Command{
+	Name:  "show",
+	Usage: showUsage,
+	Help:  showHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(showUsage, "expected <username> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			u, err := app.users.GetByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			return writeUsers(e.Stdout, cfg.Output, *u)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
+
+var restoreCmd = cli.Command{
+	Name:  "restore",
+	Usage: restoreUsage,
+	Help:  restoreHelp,
+	Flags: formatFlag,
+	Action: func(ctx context.Context, e *cli.Env, target any) cli.ExitStatus {
+		cfg := target.(*core.Config)
+		if len(e.Args) != 1 {
+			e.PrintUsageErr(restoreUsage, "expected <username> arg, but got %d args", len(e.Args))
+			return cli.ExitUsageError
+		}
+
+		err := run(ctx, e, cfg, func(ctx context.Context, app *application) error {
+			u, err := app.users.GetDeletedByName(ctx, e.Args[0])
+			if err != nil {
+				return err
+			}
+			if u, err = app.users.Restore(ctx, u.Id); err != nil {
+				return err
+			}
+			return writeUsers(e.Stdout, cfg.Output, *u)
+		})
+		if err != nil {
+			return cli.ExitFailure
+		}
+		return cli.ExitSuccess
+	},
+}
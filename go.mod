@@ -2,18 +2,14 @@ module github.com/jonathonwebb/tilde
 
 go 1.24.2
 
-require github.com/mattn/go-sqlite3 v1.14.28
-
 require (
-	golang.org/x/net v0.38.0 // indirect
-	gopkg.in/cenkalti/backoff.v1 v1.1.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.28
+	golang.org/x/term v0.30.0
 )
 
 require (
 	github.com/evanw/esbuild v0.25.5
 	github.com/google/go-cmp v0.7.0
 	github.com/gorilla/websocket v1.5.3
-	github.com/r3labs/sse/v2 v2.10.0
-	golang.org/x/sync v0.14.0
 	golang.org/x/sys v0.31.0 // indirect
 )
@@ -0,0 +1,180 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type Action string
+
+var (
+	Create        Action = "create"
+	Read          Action = "read"
+	Update        Action = "update"
+	Delete        Action = "delete"
+	ManageMembers Action = "manage-members"
+)
+
+// Subject is the user an action is performed by. The zero Subject is anonymous.
+type Subject struct {
+	UserId int64
+}
+
+func (s Subject) Anonymous() bool {
+	return s.UserId == 0
+}
+
+// Resource identifies what an action is performed on. OrgId is the org the
+// resource belongs to and OwnerId the user who owns it, when either applies.
+type Resource struct {
+	Kind    string
+	Id      int64
+	OrgId   int64
+	OwnerId int64
+}
+
+func (r Resource) String() string {
+	if r.Id == 0 {
+		return r.Kind
+	}
+	return fmt.Sprintf("%s %d", r.Kind, r.Id)
+}
+
+// Relation is a relationship between a subject and a resource that a rule can
+// grant an action to.
+type Relation string
+
+var (
+	Authenticated Relation = "authenticated"
+	Owner         Relation = "owner"
+	OrgMember     Relation = "org member"
+	OrgAdmin      Relation = "org admin"
+	OrgOwner      Relation = "org owner"
+)
+
+// orgRoles maps org relations to the lowest role that satisfies them.
+var orgRoles = map[Relation]store.Role{
+	OrgMember: store.RoleMember,
+	OrgAdmin:  store.RoleAdmin,
+	OrgOwner:  store.RoleOwner,
+}
+
+var roleRanks = map[store.Role]int{
+	store.RoleMember: 1,
+	store.RoleAdmin:  2,
+	store.RoleOwner:  3,
+}
+
+// Rule grants Action on resources of Kind to subjects with any of the Allow
+// relations.
+type Rule struct {
+	Kind   string
+	Action Action
+	Allow  []Relation
+}
+
+type Policy []Rule
+
+// DefaultPolicy covers users, orgs, generic org resources owned by a user, and
+// search.
+var DefaultPolicy = Policy{
+	{Kind: "user", Action: Read, Allow: []Relation{Authenticated}},
+	{Kind: "user", Action: Update, Allow: []Relation{Owner}},
+	{Kind: "user", Action: Delete, Allow: []Relation{Owner}},
+
+	{Kind: "org", Action: Create, Allow: []Relation{Authenticated}},
+	{Kind: "org", Action: Read, Allow: []Relation{OrgMember}},
+	{Kind: "org", Action: Update, Allow: []Relation{OrgAdmin}},
+	{Kind: "org", Action: Delete, Allow: []Relation{OrgOwner}},
+	{Kind: "org", Action: ManageMembers, Allow: []Relation{OrgAdmin}},
+
+	{Kind: "resource", Action: Create, Allow: []Relation{OrgMember}},
+	{Kind: "resource", Action: Read, Allow: []Relation{Owner, OrgMember}},
+	{Kind: "resource", Action: Update, Allow: []Relation{Owner, OrgAdmin}},
+	{Kind: "resource", Action: Delete, Allow: []Relation{Owner, OrgAdmin}},
+
+	{Kind: "search", Action: Read, Allow: []Relation{Authenticated}},
+}
+
+func (p Policy) rule(kind string, action Action) (Rule, bool) {
+	for _, r := range p {
+		if r.Kind == kind && r.Action == action {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// RoleSource looks up a user's role in an org, returning store.ErrNotFound
+// when the user is not a member.
+type RoleSource interface {
+	Role(ctx context.Context, orgId, userId int64) (store.Role, error)
+}
+
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+func (d Decision) String() string {
+	if d.Allowed {
+		return "allow: " + d.Reason
+	}
+	return "deny: " + d.Reason
+}
+
+type Authorizer struct {
+	Policy Policy
+	Roles  RoleSource
+}
+
+// Check decides whether sub may perform action on res. Anything the policy
+// does not explicitly allow is denied.
+func (a *Authorizer) Check(ctx context.Context, sub Subject, action Action, res Resource) (Decision, error) {
+	rule, ok := a.Policy.rule(res.Kind, action)
+	if !ok {
+		return Decision{Reason: fmt.Sprintf("no rule for %s on %s", action, res.Kind)}, nil
+	}
+	if sub.Anonymous() {
+		return Decision{Reason: "subject is anonymous"}, nil
+	}
+
+	var role store.Role
+	var roleKnown bool
+	for _, rel := range rule.Allow {
+		switch rel {
+		case Authenticated:
+			return Decision{Allowed: true, Reason: "subject is authenticated"}, nil
+		case Owner:
+			if res.OwnerId != 0 && res.OwnerId == sub.UserId {
+				return Decision{Allowed: true, Reason: fmt.Sprintf("user %d owns %s", sub.UserId, res)}, nil
+			}
+		default:
+			want, ok := orgRoles[rel]
+			if !ok || res.OrgId == 0 {
+				continue
+			}
+			if !roleKnown {
+				var err error
+				role, err = a.Roles.Role(ctx, res.OrgId, sub.UserId)
+				if err != nil && !errors.Is(err, store.ErrNotFound) {
+					return Decision{}, err
+				}
+				roleKnown = true
+			}
+			if roleRanks[role] >= roleRanks[want] {
+				return Decision{Allowed: true, Reason: fmt.Sprintf("user %d is %s of org %d", sub.UserId, role, res.OrgId)}, nil
+			}
+		}
+	}
+
+	allow := make([]string, len(rule.Allow))
+	for i, rel := range rule.Allow {
+		allow[i] = string(rel)
+	}
+	return Decision{Reason: fmt.Sprintf("%s on %s requires %s", action, res, strings.Join(allow, " or "))}, nil
+}
@@ -0,0 +1,113 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/authz"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type roles map[[2]int64]store.Role
+
+func (r roles) Role(ctx context.Context, orgId, userId int64) (store.Role, error) {
+	role, ok := r[[2]int64{orgId, userId}]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return role, nil
+}
+
+const (
+	alice = 1 // owner of org 10
+	bob   = 2 // member of org 10
+	carol = 3 // not a member
+)
+
+func newAuthorizer() *authz.Authorizer {
+	return &authz.Authorizer{
+		Policy: authz.DefaultPolicy,
+		Roles: roles{
+			{10, alice}: store.RoleOwner,
+			{10, bob}:   store.RoleMember,
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	org := authz.Resource{Kind: "org", Id: 10, OrgId: 10}
+	doc := authz.Resource{Kind: "resource", Id: 5, OrgId: 10, OwnerId: carol}
+
+	tests := []struct {
+		name   string
+		sub    int64
+		action authz.Action
+		res    authz.Resource
+		want   authz.Decision
+	}{
+		{"anonymous", 0, authz.Read, org, authz.Decision{Reason: "subject is anonymous"}},
+		{"unknown action", alice, "frobnicate", org, authz.Decision{Reason: "no rule for frobnicate on org"}},
+		{"member reads org", bob, authz.Read, org, authz.Decision{Allowed: true, Reason: "user 2 is member of org 10"}},
+		{"member updates org", bob, authz.Update, org, authz.Decision{Reason: "update on org 10 requires org admin"}},
+		{"owner deletes org", alice, authz.Delete, org, authz.Decision{Allowed: true, Reason: "user 1 is owner of org 10"}},
+		{"non-member reads org", carol, authz.Read, org, authz.Decision{Reason: "read on org 10 requires org member"}},
+		{"owner updates own resource", carol, authz.Update, doc, authz.Decision{Allowed: true, Reason: "user 3 owns resource 5"}},
+		{"member updates resource", bob, authz.Update, doc, authz.Decision{Reason: "update on resource 5 requires owner or org admin"}},
+		{"org owner updates resource", alice, authz.Update, doc, authz.Decision{Allowed: true, Reason: "user 1 is owner of org 10"}},
+		{"user updates self", bob, authz.Update, authz.Resource{Kind: "user", Id: bob, OwnerId: bob}, authz.Decision{Allowed: true, Reason: "user 2 owns user 2"}},
+		{"user updates other", bob, authz.Update, authz.Resource{Kind: "user", Id: alice, OwnerId: alice}, authz.Decision{Reason: "update on user 1 requires owner"}},
+	}
+	a := newAuthorizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Check(t.Context(), authz.Subject{UserId: tt.sub}, tt.action, tt.res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("decision mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequire(t *testing.T) {
+	a := newAuthorizer()
+	m := http.NewServeMux()
+	m.Handle("PATCH /orgs/{org}", a.Require(authz.Update, authz.OrgPath("org"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	m.Handle("/search", a.Require(authz.Read, authz.KindOf("search"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	tests := []struct {
+		name string
+		sub  int64
+		path string
+		want int
+	}{
+		{"anonymous", 0, "/orgs/10", http.StatusUnauthorized},
+		{"denied", bob, "/orgs/10", http.StatusForbidden},
+		{"allowed", alice, "/orgs/10", http.StatusNoContent},
+		{"bad resource", alice, "/orgs/x", http.StatusNotFound},
+		{"anonymous search", 0, "/search", http.StatusUnauthorized},
+		{"search", bob, "/search", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, tt.path, nil)
+			if tt.sub != 0 {
+				r = r.WithContext(authz.WithSubject(r.Context(), authz.Subject{UserId: tt.sub}))
+			}
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("want status %d, but got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
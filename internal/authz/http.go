@@ -0,0 +1,67 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+type subjectKey struct{}
+
+func WithSubject(ctx context.Context, sub Subject) context.Context {
+	return context.WithValue(ctx, subjectKey{}, sub)
+}
+
+// SubjectFrom returns the subject stored in ctx, or the anonymous subject.
+func SubjectFrom(ctx context.Context) Subject {
+	sub, _ := ctx.Value(subjectKey{}).(Subject)
+	return sub
+}
+
+// Require only calls next when the request's subject may perform action on the
+// resource returned by resource. Anonymous subjects that are denied get 401,
+// others 403.
+func (a *Authorizer) Require(action Action, resource func(*http.Request) (Resource, error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := resource(r)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		sub := SubjectFrom(r.Context())
+		d, err := a.Check(r.Context(), sub, action, res)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !d.Allowed {
+			status := http.StatusForbidden
+			if sub.Anonymous() {
+				status = http.StatusUnauthorized
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// KindOf resolves requests to the resource kind, for routes that act on no
+// resource in particular.
+func KindOf(kind string) func(*http.Request) (Resource, error) {
+	return func(*http.Request) (Resource, error) {
+		return Resource{Kind: kind}, nil
+	}
+}
+
+// OrgPath resolves the org resource named by the id in path wildcard name.
+func OrgPath(name string) func(*http.Request) (Resource, error) {
+	return func(r *http.Request) (Resource, error) {
+		id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+		if err != nil {
+			return Resource{}, err
+		}
+		return Resource{Kind: "org", Id: id, OrgId: id}, nil
+	}
+}
@@ -0,0 +1,356 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+const (
+	prefix      = "tilde-"
+	dataExt     = ".db.gz"
+	manifestExt = ".json"
+	stampLayout = "20060102T150405.000000000Z"
+)
+
+type Manifest struct {
+	File string `json:"file"`
+	// Base is set on incremental snapshots, to the File of the full snapshot
+	// they and the incremental snapshots before them apply to.
+	Base          string    `json:"base,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int64     `json:"schema_version"`
+	Size          int64     `json:"size"`
+	SHA256        string    `json:"sha256"`
+}
+
+// Create writes a compressed snapshot of db and its manifest to dir, using the
+// sqlite online backup API so that writers are not blocked.
+func Create(ctx context.Context, db *core.DB, dir string, now time.Time) (_ *Manifest, err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := snapshot(ctx, db.Reader(), dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, os.Remove(tmp))
+	}()
+	return writeFull(ctx, tmp, dir, now)
+}
+
+// writeFull compresses the snapshot at p into dir as a full backup.
+func writeFull(ctx context.Context, p, dir string, now time.Time) (*Manifest, error) {
+	version, err := snapshotVersion(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	stem := prefix + now.UTC().Format(stampLayout)
+	m := &Manifest{
+		File:          stem + dataExt,
+		CreatedAt:     now.UTC(),
+		SchemaVersion: version,
+	}
+	if err := compress(p, filepath.Join(dir, m.File), m); err != nil {
+		return nil, err
+	}
+	if err := writeManifest(filepath.Join(dir, stem+manifestExt), m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// snapshot copies the database into a temporary file in dir.
+func snapshot(ctx context.Context, pool *sql.DB, dir string) (string, error) {
+	f, err := os.CreateTemp(dir, ".snapshot-*.db")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	if err := f.Close(); err != nil {
+		return "", errors.Join(err, os.Remove(tmp))
+	}
+
+	err = core.RawConn(ctx, pool, func(src *sqlite3.SQLiteConn) error {
+		return copyDb(tmp, src, true)
+	})
+	if err != nil {
+		return "", errors.Join(err, os.Remove(tmp))
+	}
+	return tmp, nil
+}
+
+// copyDb runs a single-step backup between the file at p and conn, into p when
+// toFile is set and from it otherwise.
+func copyDb(p string, conn *sqlite3.SQLiteConn, toFile bool) (err error) {
+	fc, err := (&sqlite3.SQLiteDriver{}).Open(p)
+	if err != nil {
+		return err
+	}
+	fileConn := fc.(*sqlite3.SQLiteConn)
+	defer func() {
+		err = errors.Join(err, fileConn.Close())
+	}()
+
+	dest, src := fileConn, conn
+	if !toFile {
+		dest, src = conn, fileConn
+	}
+	b, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+	if _, err := b.Step(-1); err != nil {
+		return errors.Join(err, b.Finish())
+	}
+	return b.Finish()
+}
+
+func snapshotVersion(ctx context.Context, p string) (_ int64, err error) {
+	db, err := sql.Open("sqlite3", p+"?_query_only=true")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = errors.Join(err, db.Close())
+	}()
+	return schemaVersion(ctx, db)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// schemaVersion returns the latest migration applied to the database q reads,
+// or -1 if none has been.
+func schemaVersion(ctx context.Context, q queryer) (int64, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT count(*) > 0 FROM sqlite_schema WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return -1, nil
+	}
+
+	var v int64
+	if err := q.QueryRowContext(ctx, "SELECT coalesce(max(version_id), -1) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+func compress(src, dest string, m *Manifest) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, in.Close())
+	}()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	h := sha256.New()
+	zw := gzip.NewWriter(out)
+	n, err := io.Copy(io.MultiWriter(zw, h), in)
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	m.Size = n
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return out.Sync()
+}
+
+func writeManifest(p string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0600)
+}
+
+func ReadManifest(p string) (*Manifest, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %v", p, err)
+	}
+	return &m, nil
+}
+
+// List returns the paths of the manifests in dir, newest first.
+func List(dir string) ([]string, error) {
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []string
+	for _, dirent := range dirents {
+		name := dirent.Name()
+		if dirent.Type().IsRegular() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, manifestExt) {
+			manifests = append(manifests, filepath.Join(dir, name))
+		}
+	}
+	// timestamps are fixed width, so names sort chronologically
+	slices.Sort(manifests)
+	slices.Reverse(manifests)
+	return manifests, nil
+}
+
+// Prune removes all but the keep newest backups in dir, returning the removed
+// manifest paths.
+func Prune(dir string, keep int) ([]string, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) <= keep {
+		return nil, nil
+	}
+
+	removed := manifests[keep:]
+	for _, p := range removed {
+		m, err := ReadManifest(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(filepath.Join(dir, m.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err := os.Remove(p); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// CheckVersion reports an error unless v is a schema version known to the
+// given migration sources.
+func CheckVersion(v int64, sources []schema.Migration) error {
+	if v == -1 {
+		return nil
+	}
+	var latest int64 = -1
+	for _, src := range sources {
+		if int64(src.Id) == v {
+			return nil
+		}
+		latest = max(latest, int64(src.Id))
+	}
+	if v > latest {
+		return fmt.Errorf("backup schema version %d is newer than latest known version %d", v, latest)
+	}
+	return fmt.Errorf("backup schema version %d is unknown", v)
+}
+
+// Restore verifies the backup described by the manifest at p and copies it
+// over db with the online backup API, so open connections see the restored
+// contents rather than a replaced file. An incremental snapshot is restored
+// by applying it and the ones before it to their full snapshot.
+func Restore(ctx context.Context, db *core.DB, p string, sources []schema.Migration) (_ *Manifest, err error) {
+	m, err := ReadManifest(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckVersion(m.SchemaVersion, sources); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(p)
+	full, increments, err := chain(dir, p, m)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := decompress(filepath.Join(dir, full.File), dir, full)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, os.Remove(tmp))
+	}()
+	for _, inc := range increments {
+		if err := applyIncremental(filepath.Join(dir, inc.File), tmp, inc); err != nil {
+			return nil, fmt.Errorf("apply %s: %w", inc.File, err)
+		}
+	}
+
+	err = core.RawConn(ctx, db.Writer(), func(dest *sqlite3.SQLiteConn) error {
+		return copyDb(tmp, dest, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func decompress(src, dir string, m *Manifest) (_ string, err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		err = errors.Join(err, in.Close())
+	}()
+
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := os.CreateTemp(dir, ".restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	tmp := out.Name()
+	defer func() {
+		err = errors.Join(err, out.Close())
+		if err != nil {
+			err = errors.Join(err, os.Remove(tmp))
+		}
+	}()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), zr)
+	if err != nil {
+		return "", err
+	}
+	if err := zr.Close(); err != nil {
+		return "", err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); n != m.Size || sum != m.SHA256 {
+		return "", fmt.Errorf("backup %s does not match its manifest", m.File)
+	}
+	return tmp, nil
+}
@@ -0,0 +1,115 @@
+package backup_test
+
+import (
+	"log/slog"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/backup"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := path.Join(dir, "backups")
+	db := openDB(t, path.Join(dir, "data.db"))
+
+	if _, err := db.ExecContext(t.Context(), `CREATE TABLE schema_migrations (id INTEGER PRIMARY KEY, version_id INTEGER UNIQUE NOT NULL);
+INSERT INTO schema_migrations (version_id) VALUES (10), (20);
+CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT UNIQUE NOT NULL);
+INSERT INTO users (username) VALUES ('alice');`); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var manifests []*backup.Manifest
+	for i := range 3 {
+		m, err := backup.Create(t.Context(), db, backupDir, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifests = append(manifests, m)
+	}
+	if got := manifests[0].SchemaVersion; got != 20 {
+		t.Errorf("want schema version = 20, but got %d", got)
+	}
+
+	removed, err := backup.Prune(backupDir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != "tilde-20250601T000000.000000000Z.json" {
+		t.Errorf("want oldest backup pruned, but got %v", removed)
+	}
+
+	listed, err := backup.List(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("want 2 backups, but got %d", len(listed))
+	}
+
+	if _, err := db.ExecContext(t.Context(), "DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unknown version", func(t *testing.T) {
+		sources := []schema.Migration{{Id: 10}}
+		if _, err := backup.Restore(t.Context(), db, listed[0], sources); err == nil {
+			t.Error("want schema version error, but got nil")
+		}
+	})
+
+	t.Run("known version", func(t *testing.T) {
+		sources := []schema.Migration{{Id: 10}, {Id: 20}}
+		m, err := backup.Restore(t.Context(), db, listed[0], sources)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(manifests[2], m); diff != "" {
+			t.Errorf("manifest mismatch (-want +got):\n%s", diff)
+		}
+
+		var got []string
+		rows, err := db.QueryContext(t.Context(), "SELECT username FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close() //nolint:errcheck
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, name)
+		}
+		if diff := cmp.Diff([]string{"alice"}, got); diff != "" {
+			t.Errorf("restored rows mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func openDB(t testing.TB, p string) *core.DB {
+	t.Helper()
+
+	cfg := core.Config{
+		DbConnString:  p,
+		DbJournalMode: "wal",
+		DbBusyTimeout: time.Second,
+	}
+	db, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
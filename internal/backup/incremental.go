@@ -0,0 +1,236 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// incrementalExt names the data files of incremental snapshots. Each holds,
+// gzipped, the page size and page count of the database followed by the
+// number and contents of every page that changed since the snapshot before.
+const incrementalExt = ".pages.gz"
+
+// errPageSize means two snapshots have different page sizes, as after a
+// VACUUM changing it, so one can't be applied to the other page by page.
+var errPageSize = errors.New("page size changed")
+
+// pageSums holds the SHA-256 sums of the pages of a snapshot, in order from
+// page 1, to find the pages the next snapshot changes without keeping a copy.
+type pageSums struct {
+	size uint32
+	sums [][sha256.Size]byte
+}
+
+// sumPages sums the first n pages of the database file f, whose pages are
+// size bytes.
+func sumPages(f io.ReaderAt, size, n uint32) (*pageSums, error) {
+	ps := &pageSums{size: size, sums: make([][sha256.Size]byte, n)}
+	page := make([]byte, size)
+	for i := range ps.sums {
+		if _, err := f.ReadAt(page, int64(i)*int64(size)); err != nil {
+			return nil, err
+		}
+		ps.sums[i] = sha256.Sum256(page)
+	}
+	return ps, nil
+}
+
+// writeIncremental writes the first n pages of the database file f that differ
+// from prev into dir, as an incremental snapshot applying to the full snapshot
+// base, and returns the sums of all n pages for the next one to compare. The
+// data file is removed if the snapshot can't be completed.
+func writeIncremental(f io.ReaderAt, size, n uint32, version int64, dir string, now time.Time, base *Manifest, prev *pageSums) (_ *Manifest, _ *pageSums, err error) {
+	if size != prev.size {
+		return nil, nil, errPageSize
+	}
+
+	stem := prefix + now.UTC().Format(stampLayout)
+	m := &Manifest{
+		File:          stem + incrementalExt,
+		Base:          base.File,
+		CreatedAt:     now.UTC(),
+		SchemaVersion: version,
+	}
+	p := filepath.Join(dir, m.File)
+	out, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+		if err != nil {
+			err = errors.Join(err, os.Remove(p))
+		}
+	}()
+
+	next := &pageSums{size: size, sums: make([][sha256.Size]byte, n)}
+	h := sha256.New()
+	zw := gzip.NewWriter(out)
+	cw := &countWriter{w: io.MultiWriter(zw, h)}
+	if err := binary.Write(cw, binary.BigEndian, [2]uint32{size, n}); err != nil {
+		return nil, nil, err
+	}
+	page := make([]byte, size)
+	for i := range next.sums {
+		if _, err := f.ReadAt(page, int64(i)*int64(size)); err != nil {
+			return nil, nil, err
+		}
+		next.sums[i] = sha256.Sum256(page)
+		if i < len(prev.sums) && next.sums[i] == prev.sums[i] {
+			continue
+		}
+		if err := binary.Write(cw, binary.BigEndian, uint32(i+1)); err != nil {
+			return nil, nil, err
+		}
+		if _, err := cw.Write(page); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := out.Sync(); err != nil {
+		return nil, nil, err
+	}
+
+	m.Size = cw.n
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if err := writeManifest(filepath.Join(dir, stem+manifestExt), m); err != nil {
+		return nil, nil, err
+	}
+	return m, next, nil
+}
+
+// applyIncremental writes the pages of the incremental snapshot at src over
+// the database file at p, verifying them against m.
+func applyIncremental(src, p string, m *Manifest) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, in.Close())
+	}()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != m.Size || hex.EncodeToString(sum[:]) != m.SHA256 {
+		return fmt.Errorf("backup %s does not match its manifest", m.File)
+	}
+
+	out, err := os.OpenFile(p, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	r := bytes.NewReader(data)
+	var header [2]uint32
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return err
+	}
+	size, pages := header[0], header[1]
+	page := make([]byte, size)
+	for r.Len() > 0 {
+		var pgno uint32
+		if err := binary.Read(r, binary.BigEndian, &pgno); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, page); err != nil {
+			return err
+		}
+		if _, err := out.WriteAt(page, int64(pgno-1)*int64(size)); err != nil {
+			return err
+		}
+	}
+	return out.Truncate(int64(pages) * int64(size))
+}
+
+// chain returns the full snapshot that the backup described by the manifest
+// m at p builds on, and the incremental snapshots to apply to it in order,
+// ending with m. A full snapshot is its own chain.
+func chain(dir, p string, m *Manifest) (*Manifest, []*Manifest, error) {
+	if m.Base == "" {
+		return m, nil, nil
+	}
+
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var increments []*Manifest
+	for _, mp := range manifests {
+		// manifests are newest first, so skip those after p
+		if filepath.Base(mp) > filepath.Base(p) {
+			continue
+		}
+		bm, err := ReadManifest(mp)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case bm.File == m.Base:
+			slices.Reverse(increments)
+			return bm, increments, nil
+		case bm.Base == m.Base:
+			increments = append(increments, bm)
+		}
+	}
+	return nil, nil, fmt.Errorf("full snapshot %s of %s is missing", m.Base, m.File)
+}
+
+// filePages returns the page size and page count of the database file f.
+func filePages(f *os.File) (uint32, uint32, error) {
+	size, err := pageSize(f)
+	if err != nil {
+		return 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	return size, uint32(info.Size() / int64(size)), nil
+}
+
+// pageSize reads the page size from the header of the database file f.
+func pageSize(f *os.File) (uint32, error) {
+	var b [2]byte
+	if _, err := f.ReadAt(b[:], 16); err != nil {
+		return 0, fmt.Errorf("read page size: %w", err)
+	}
+	size := uint32(binary.BigEndian.Uint16(b[:]))
+	if size == 1 {
+		// the largest page size doesn't fit in two bytes
+		size = 65536
+	}
+	return size, nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
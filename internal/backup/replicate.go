@@ -0,0 +1,344 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+// maxBackoff bounds how long replication waits to retry after failing.
+const maxBackoff = 15 * time.Minute
+
+// Replicator ships snapshots of a database to a replica directory. A snapshot
+// is taken every Interval if the database has changed since the previous one.
+// Every Snapshot a full snapshot is taken, and in between only the pages that
+// changed are, as incremental snapshots applying to it. Full snapshots are
+// removed with their incremental ones once a newer full snapshot is older than
+// Retain, so the replica holds about Retain/Snapshot + 1 copies of the
+// database, besides the changed pages.
+type Replicator struct {
+	DB       *core.DB
+	Dir      string
+	Interval time.Duration
+	Snapshot time.Duration
+	Retain   time.Duration
+	Log      *slog.Logger
+
+	full  *Manifest // the full snapshot the latest snapshots apply to
+	pages *pageSums // the pages of the latest snapshot
+}
+
+// Run replicates until ctx is done. Failed snapshots are logged and retried
+// after a backoff.
+func (r *Replicator) Run(ctx context.Context) (err error) {
+	if r.DB.InMemory() {
+		return errors.New("can't replicate an in-memory database")
+	}
+
+	conn, err := r.DB.Reader().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	r.Log.Info("replicating", "dir", r.Dir, "interval", r.Interval, "snapshot", r.Snapshot, "retain", r.Retain)
+
+	var last int64 = -1
+	failures := 0
+	for {
+		wait := r.Interval
+		if err := r.syncChanged(ctx, conn, &last); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			failures++
+			wait = max(r.Interval, min(r.Interval<<min(failures, 10), maxBackoff))
+			r.Log.Error("replication failed", "err", err, "failures", failures, "retry", wait)
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// syncChanged takes a snapshot if the database has changed since last, the
+// data version of the latest snapshot.
+func (r *Replicator) syncChanged(ctx context.Context, conn *sql.Conn, last *int64) error {
+	version, err := dataVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if version == *last {
+		return nil
+	}
+	if err := r.Sync(ctx, time.Now()); err != nil {
+		return err
+	}
+	*last = version
+	return nil
+}
+
+// Sync takes a snapshot and applies retention. The snapshot is full if no
+// full snapshot was taken within Snapshot, and incremental otherwise.
+func (r *Replicator) Sync(ctx context.Context, now time.Time) error {
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+
+	var m *Manifest
+	var err error
+	if r.full != nil && now.Sub(r.full.CreatedAt) < r.Snapshot {
+		m, err = r.syncIncremental(ctx, now)
+		if errors.Is(err, errPageSize) {
+			m, err = nil, nil
+		}
+		if err != nil {
+			return fmt.Errorf("incremental snapshot: %v", err)
+		}
+	}
+	if m == nil {
+		if m, err = r.syncFull(ctx, now); err != nil {
+			return fmt.Errorf("snapshot: %v", err)
+		}
+	}
+	r.Log.Debug("replicated snapshot", "file", m.File, "size", m.Size)
+
+	removed, err := PruneBefore(r.Dir, now.Add(-r.Retain))
+	if err != nil {
+		return fmt.Errorf("prune: %v", err)
+	}
+	for _, p := range removed {
+		r.Log.Debug("removed snapshot", "manifest", p)
+	}
+	return nil
+}
+
+// syncFull writes a full snapshot from a copy of the database.
+func (r *Replicator) syncFull(ctx context.Context, now time.Time) (_ *Manifest, err error) {
+	tmp, err := snapshot(ctx, r.DB.Reader(), r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, os.Remove(tmp))
+	}()
+
+	m, err := writeFull(ctx, tmp, r.Dir, now)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	size, n, err := filePages(f)
+	if err != nil {
+		return nil, err
+	}
+	if r.pages, err = sumPages(f, size, n); err != nil {
+		return nil, err
+	}
+	r.full = m
+	return m, nil
+}
+
+// syncIncremental writes the pages changed since the latest snapshot. They are
+// read from the database file itself when the WAL holds no commits, and from
+// a copy of the database otherwise.
+func (r *Replicator) syncIncremental(ctx context.Context, now time.Time) (*Manifest, error) {
+	m, ok, err := r.syncFile(ctx, now)
+	if ok || err != nil {
+		return m, err
+	}
+	r.Log.Debug("wal not checkpointed, copying database")
+	return r.syncCopy(ctx, now)
+}
+
+// syncFile writes an incremental snapshot from the database file, after
+// trying to checkpoint the WAL into it. It reports false, writing nothing, if
+// commits were left in the WAL.
+func (r *Replicator) syncFile(ctx context.Context, now time.Time) (_ *Manifest, _ bool, err error) {
+	conn, err := r.DB.Reader().Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+	if err := checkpoint(ctx, conn); err != nil {
+		return nil, false, err
+	}
+
+	// The read transaction keeps checkpoints from writing to the database
+	// file, so if the WAL is empty once it has started, the file holds
+	// exactly what the transaction sees until it ends.
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		err = errors.Join(err, tx.Rollback())
+	}()
+	version, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return nil, false, err
+	}
+	var p string
+	var size, n uint32
+	if err := tx.QueryRowContext(ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&p); err != nil {
+		return nil, false, err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT page_size, page_count FROM pragma_page_size, pragma_page_count").Scan(&size, &n); err != nil {
+		return nil, false, err
+	}
+	if info, err := os.Stat(p + "-wal"); err == nil && info.Size() > 0 {
+		return nil, false, nil
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	m, pages, err := writeIncremental(f, size, n, version, r.Dir, now, r.full, r.pages)
+	if err != nil {
+		return nil, false, err
+	}
+	r.pages = pages
+	return m, true, nil
+}
+
+// syncCopy writes an incremental snapshot from a copy of the database.
+func (r *Replicator) syncCopy(ctx context.Context, now time.Time) (_ *Manifest, err error) {
+	tmp, err := snapshot(ctx, r.DB.Reader(), r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, os.Remove(tmp))
+	}()
+
+	version, err := snapshotVersion(ctx, tmp)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(tmp)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	size, n, err := filePages(f)
+	if err != nil {
+		return nil, err
+	}
+	m, pages, err := writeIncremental(f, size, n, version, r.Dir, now, r.full, r.pages)
+	if err != nil {
+		return nil, err
+	}
+	r.pages = pages
+	return m, nil
+}
+
+// checkpoint tries to move every commit in the WAL into the database file and
+// truncate the WAL, giving up rather than waiting on other connections.
+func checkpoint(ctx context.Context, conn *sql.Conn) (err error) {
+	var timeout int
+	if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&timeout); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA busy_timeout = 0"); err != nil {
+		return err
+	}
+	defer func() {
+		_, resetErr := conn.ExecContext(ctx, "PRAGMA busy_timeout = "+strconv.Itoa(timeout))
+		err = errors.Join(err, resetErr)
+	}()
+	_, err = conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	return err
+}
+
+// dataVersion changes whenever another connection commits to the database.
+func dataVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var v int64
+	err := conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&v)
+	return v, err
+}
+
+// PruneBefore removes the backups in dir that restoring to t or later doesn't
+// need: those older than the newest full backup created at or before t.
+func PruneBefore(dir string, t time.Time) ([]string, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var covered bool
+	for _, p := range manifests {
+		m, err := ReadManifest(p)
+		if err != nil {
+			return nil, err
+		}
+		if !covered {
+			covered = m.Base == "" && !m.CreatedAt.After(t)
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, m.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err := os.Remove(p); err != nil {
+			return nil, err
+		}
+		removed = append(removed, p)
+	}
+	return removed, nil
+}
+
+// Find returns the manifest of the newest backup in dir created at or before
+// t, or the newest backup if t is zero.
+func Find(dir string, t time.Time) (string, error) {
+	manifests, err := List(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range manifests {
+		if t.IsZero() {
+			return p, nil
+		}
+		m, err := ReadManifest(p)
+		if err != nil {
+			return "", err
+		}
+		if !m.CreatedAt.After(t) {
+			return p, nil
+		}
+	}
+	if t.IsZero() {
+		return "", fmt.Errorf("no backups in %s", dir)
+	}
+	return "", fmt.Errorf("no backups in %s at or before %s", dir, t.Format(time.RFC3339))
+}
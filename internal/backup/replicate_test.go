@@ -0,0 +1,296 @@
+package backup_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/backup"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func TestReplicator(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, path.Join(dir, "data.db"))
+	r := &backup.Replicator{
+		DB:     db,
+		Dir:    path.Join(dir, "replica"),
+		Retain: 2 * time.Hour,
+		Log:    slog.New(slog.DiscardHandler),
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		if _, err := db.ExecContext(t.Context(), "CREATE TABLE t"+string(rune('a'+i))+" (id INTEGER PRIMARY KEY)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Sync(t.Context(), start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifests, err := backup.List(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 3 {
+		t.Errorf("want 3 snapshots within retention, but got %d", len(manifests))
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"latest", time.Time{}, "tilde-20250601T030000.000000000Z.json"},
+		{"exact", start.Add(2 * time.Hour), "tilde-20250601T020000.000000000Z.json"},
+		{"between", start.Add(90 * time.Minute), "tilde-20250601T010000.000000000Z.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backup.Find(r.Dir, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(got) != tt.want {
+				t.Errorf("want %s, but got %s", tt.want, filepath.Base(got))
+			}
+		})
+	}
+
+	t.Run("before retention", func(t *testing.T) {
+		if _, err := backup.Find(r.Dir, start.Add(30*time.Minute)); err == nil {
+			t.Error("want error for time before oldest snapshot, but got nil")
+		}
+	})
+}
+
+func TestReplicatorIncremental(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, path.Join(dir, "data.db"))
+	if _, err := db.ExecContext(t.Context(), "CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	var logs strings.Builder
+	r := &backup.Replicator{
+		DB:       db,
+		Dir:      path.Join(dir, "replica"),
+		Snapshot: 2 * time.Hour,
+		Retain:   2 * time.Hour,
+		Log:      slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if _, err := db.ExecContext(t.Context(), "INSERT INTO users (username) VALUES (?)", fmt.Sprintf("user%d", i)); err != nil {
+			t.Fatal(err)
+		}
+		// A reader still seeing the insert in the WAL keeps it from being
+		// checkpointed, so the incremental snapshot at 1h is taken from a copy.
+		var tx *sql.Tx
+		if i == 1 {
+			var err error
+			if tx, err = db.Reader().BeginTx(t.Context(), nil); err != nil {
+				t.Fatal(err)
+			}
+			usernamesIn(t, tx)
+		}
+		if err := r.Sync(t.Context(), start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if tx != nil {
+			if err := tx.Rollback(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if n := strings.Count(logs.String(), "wal not checkpointed"); n != 1 {
+		t.Errorf("want 1 incremental snapshot copying the database, but got %d", n)
+	}
+
+	// The full snapshot at 0h and its incremental one at 1h are no longer
+	// needed to restore to 2h or later.
+	manifests, err := backup.List(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range manifests {
+		m, err := backup.ReadManifest(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m.File+" "+m.Base)
+	}
+	want := []string{
+		"tilde-20250601T040000.000000000Z.db.gz ",
+		"tilde-20250601T030000.000000000Z.pages.gz tilde-20250601T020000.000000000Z.db.gz",
+		"tilde-20250601T020000.000000000Z.db.gz ",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("snapshots mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want []string
+	}{
+		{start.Add(2 * time.Hour), []string{"user0", "user1", "user2"}},
+		{start.Add(3*time.Hour + 30*time.Minute), []string{"user0", "user1", "user2", "user3"}},
+		{time.Time{}, []string{"user0", "user1", "user2", "user3", "user4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.at.Format(time.Kitchen), func(t *testing.T) {
+			p, err := backup.Find(r.Dir, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := openDB(t, path.Join(t.TempDir(), "restored.db"))
+			if _, err := backup.Restore(t.Context(), restored, p, nil); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, usernames(t, restored)); diff != "" {
+				t.Errorf("restored users mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReplicatorIncrementalFails(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, path.Join(dir, "data.db"))
+	r := &backup.Replicator{
+		DB:       db,
+		Dir:      path.Join(dir, "replica"),
+		Snapshot: 2 * time.Hour,
+		Retain:   2 * time.Hour,
+		Log:      slog.New(slog.DiscardHandler),
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := r.Sync(t.Context(), start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(t.Context(), "CREATE TABLE users (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	// The manifest can't be written while a dir is in its way.
+	if err := os.Mkdir(path.Join(r.Dir, "tilde-20250601T010000.000000000Z.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync(t.Context(), start.Add(time.Hour)); err == nil {
+		t.Fatal("want error writing the manifest, but got nil")
+	}
+
+	dirents, err := os.ReadDir(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, dirent := range dirents {
+		got = append(got, dirent.Name())
+	}
+	want := []string{
+		"tilde-20250601T000000.000000000Z.db.gz",
+		"tilde-20250601T000000.000000000Z.json",
+		"tilde-20250601T010000.000000000Z.json",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("replica dir mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReplicatorRetries(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, path.Join(dir, "data.db"))
+	// The replica dir can't be created while a file is in its way.
+	blocker := path.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := &backup.Replicator{
+		DB:       db,
+		Dir:      path.Join(blocker, "replica"),
+		Interval: 10 * time.Millisecond,
+		Snapshot: time.Hour,
+		Retain:   time.Hour,
+		Log:      slog.New(slog.DiscardHandler),
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if manifests, err := backup.List(r.Dir); err == nil && len(manifests) > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("want a snapshot once the replica dir can be created, but got none")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplicatorInMemory(t *testing.T) {
+	db := openDB(t, "file::memory:?cache=shared")
+	r := &backup.Replicator{
+		DB:       db,
+		Dir:      t.TempDir(),
+		Interval: time.Minute,
+		Log:      slog.New(slog.DiscardHandler),
+	}
+	if err := r.Run(t.Context()); err == nil {
+		t.Error("want error replicating an in-memory database, but got nil")
+	}
+}
+
+func usernames(t testing.TB, db *core.DB) []string {
+	t.Helper()
+	return usernamesIn(t, db.Reader())
+}
+
+func usernamesIn(t testing.TB, q interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) []string {
+	t.Helper()
+
+	rows, err := q.QueryContext(t.Context(), "SELECT username FROM users ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close() //nolint:errcheck
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
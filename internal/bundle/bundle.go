@@ -0,0 +1,64 @@
+// Package bundle holds the esbuild settings shared by tilde assets and the dev
+// server, so that development builds match production ones but for hashing and
+// hot module replacement.
+package bundle
+
+import (
+	"maps"
+	"path"
+	"slices"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+// HMRModule is the module, in the assets dir, whose hot function lets the
+// components of a module importing it be swapped into a page in place.
+const HMRModule = "hmr.ts"
+
+// Vendor maps the packages that dev builds leave out of each bundle to the
+// outputs, in the build dir, that dev builds them to instead. Hot-swapped
+// modules then share the page's copy of them rather than each running their
+// own. Pages resolve them with an import map.
+var Vendor = map[string]string{
+	"preact":       "vendor/preact",
+	"preact/hooks": "vendor/preact-hooks",
+}
+
+// Options returns the options bundling each entrypoint in assetsDir's
+// entrypoints dir into staticDir's build dir.
+func Options(assetsDir, staticDir string) esbuild.BuildOptions {
+	entrypoints := path.Join(assetsDir, "entrypoints")
+	return esbuild.BuildOptions{
+		EntryPoints: []string{
+			path.Join(entrypoints, "**/*.ts"),
+			path.Join(entrypoints, "**/*.tsx"),
+			path.Join(entrypoints, "**/*.css"),
+		},
+		Outbase:     entrypoints,
+		Outdir:      path.Join(staticDir, "build"),
+		Sourcemap:   esbuild.SourceMapInline,
+		Bundle:      true,
+		Format:      esbuild.FormatESModule,
+		JSX:         esbuild.JSXTransform,
+		JSXFactory:  "h",
+		JSXFragment: "Fragment",
+		Define:      map[string]string{"__TILDE_HMR__": "false"},
+	}
+}
+
+// DevOptions returns Options for the dev server, building the Vendor
+// packages apart and enabling hot module replacement. The metafile tells
+// which modules each build changed.
+func DevOptions(assetsDir, staticDir string) esbuild.BuildOptions {
+	opts := Options(assetsDir, staticDir)
+	opts.Define["__TILDE_HMR__"] = "true"
+	opts.Metafile = true
+	for _, pkg := range slices.Sorted(maps.Keys(Vendor)) {
+		opts.External = append(opts.External, pkg)
+		opts.EntryPointsAdvanced = append(opts.EntryPointsAdvanced, esbuild.EntryPoint{
+			InputPath:  pkg,
+			OutputPath: Vendor[pkg],
+		})
+	}
+	return opts
+}
@@ -0,0 +1,102 @@
+package bundle_test
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/bundle"
+)
+
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// build runs opts from dir, returning its outputs by path in static.
+func build(t testing.TB, dir, static string, opts esbuild.BuildOptions) map[string]string {
+	t.Helper()
+	opts.AbsWorkingDir = dir
+	opts.Write = false
+	res := esbuild.Build(opts)
+	if len(res.Errors) > 0 {
+		t.Fatalf("build failed: %v", res.Errors)
+	}
+	if len(res.Warnings) > 0 {
+		t.Errorf("want no build warnings, but got %v", res.Warnings)
+	}
+
+	outputs := map[string]string{}
+	for _, o := range res.OutputFiles {
+		rel, err := filepath.Rel(filepath.Join(dir, static), o.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs[filepath.ToSlash(rel)] = string(o.Contents)
+	}
+	return outputs
+}
+
+func TestOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"assets/entrypoints/main.tsx":      `const h = (tag: string) => tag; document.title = <p />;`,
+		"assets/entrypoints/main.css":      `p { color: red; }`,
+		"assets/entrypoints/admin/page.ts": `export const page = 1;`,
+		"assets/entrypoints/notes.md":      `not an entrypoint`,
+	})
+
+	outputs := build(t, dir, "static", bundle.Options("assets", "static"))
+	got := slices.Sorted(maps.Keys(outputs))
+	want := []string{"build/admin/page.js", "build/main.css", "build/main.js"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("outputs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDevOptions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"node_modules/preact/package.json": `{"name": "preact", "exports": {".": "./preact.js", "./hooks": "./hooks.js"}}`,
+		"node_modules/preact/preact.js":    `export const h = () => {};`,
+		"node_modules/preact/hooks.js":     `import { h } from "preact"; export const useState = () => h;`,
+		"assets/entrypoints/main.ts":       `import { useState } from "preact/hooks"; if (__TILDE_HMR__) useState();`,
+		"assets/entrypoints/main.css":      `p { color: red; }`,
+		"assets/entrypoints/app.tsx":       `export {};`,
+	})
+
+	for _, tt := range []struct {
+		opts esbuild.BuildOptions
+		hmr  bool
+	}{
+		{bundle.Options("assets", "static"), false},
+		{bundle.DevOptions("assets", "static"), true},
+	} {
+		outputs := build(t, dir, "static", tt.opts)
+		main := outputs["build/main.js"]
+		if got := strings.Contains(main, `from "preact/hooks"`); got != tt.hmr {
+			t.Errorf("with hmr %v, want preact/hooks external = %v, but got:\n%s", tt.hmr, tt.hmr, main)
+		}
+		for _, out := range bundle.Vendor {
+			if _, got := outputs["build/"+out+".js"]; got != tt.hmr {
+				t.Errorf("with hmr %v, want vendor output %s = %v", tt.hmr, out, tt.hmr)
+			}
+		}
+		if got := strings.Contains(main, "if (true)"); got != tt.hmr {
+			t.Errorf("with hmr %v, want __TILDE_HMR__ = %v, but got:\n%s", tt.hmr, tt.hmr, main)
+		}
+	}
+}
@@ -13,10 +13,15 @@ import (
 
 type Env struct {
 	Log            *log.Logger
+	Stdin          io.Reader
 	Stderr, Stdout io.Writer
 	Args           []string
 	Vars           map[string]string
 	Meta           map[string]any
+
+	// RootArgs are the flag args the first command executed, the root, was
+	// given, for commands that run another one with the same root flags.
+	RootArgs []string
 }
 
 func DefaultEnv(meta map[string]any) *Env {
@@ -29,6 +34,7 @@ func DefaultEnv(meta map[string]any) *Env {
 	}
 	return &Env{
 		Log:    log.New(os.Stderr, "", 0),
+		Stdin:  os.Stdin,
 		Stderr: os.Stderr,
 		Stdout: os.Stdout,
 		Args:   os.Args,
@@ -37,6 +43,14 @@ func DefaultEnv(meta map[string]any) *Env {
 	}
 }
 
+// Actor describes the user running the command for audit records.
+func (e *Env) Actor() string {
+	if user := e.Vars["USER"]; user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
 //nolint:errcheck
 func (e *Env) PrintUsageErr(usage, format string, a ...any) {
 	fmt.Fprintf(e.Stderr, "%s\n", fmt.Sprintf(format, a...))
@@ -71,14 +85,10 @@ type Command struct {
 	flags *flag.FlagSet
 }
 
-func (c *Command) ensureFlagSet() {
-	if c.flags == nil {
-		c.flags = flag.NewFlagSet(c.Name, flag.ContinueOnError)
-	}
-}
-
+// initFlagSet starts each execution with a fresh flag set, so that a command
+// can be executed more than once.
 func (c *Command) initFlagSet() {
-	c.ensureFlagSet()
+	c.flags = flag.NewFlagSet(c.Name, flag.ContinueOnError)
 	c.flags.Usage = func() {}
 	c.flags.SetOutput(io.Discard)
 }
@@ -147,6 +157,9 @@ func (c *Command) Execute(ctx context.Context, env *Env, target any) ExitStatus
 		return c.error(env, flagErr)
 	}
 
+	if env.RootArgs == nil {
+		env.RootArgs = env.Args[1 : len(env.Args)-c.flags.NArg()]
+	}
 	env.Args = c.flags.Args()
 
 	if c.Action != nil {
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type OutputFormat string
+
+var (
+	TableOutput OutputFormat = "table"
+	JSONOutput  OutputFormat = "json"
+	CSVOutput   OutputFormat = "csv"
+)
+
+func (f *OutputFormat) MarshalText() ([]byte, error) {
+	return []byte(*f), nil
+}
+
+func (f *OutputFormat) UnmarshalText(text []byte) error {
+	switch v := OutputFormat(strings.ToLower(string(text))); v {
+	case TableOutput, JSONOutput, CSVOutput:
+		*f = v
+	default:
+		return fmt.Errorf("expected one of: table, json, csv")
+	}
+	return nil
+}
+
+// WriteRows writes rows of values under the named columns in format. JSON
+// output is an array of objects with keys in column order.
+func WriteRows(w io.Writer, format OutputFormat, cols []string, rows [][]any) error {
+	switch format {
+	case JSONOutput:
+		return writeJSON(w, cols, rows)
+	case CSVOutput:
+		return writeCSV(w, cols, rows)
+	default:
+		return writeTable(w, cols, rows)
+	}
+}
+
+func writeTable(w io.Writer, cols []string, rows [][]any) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(cols, "\t"))) //nolint:errcheck
+	for _, row := range rows {
+		fields := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				fields[i] = "NULL"
+			} else {
+				fields[i] = text(v)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(fields, "\t")) //nolint:errcheck
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, cols []string, rows [][]any) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = text(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, cols []string, rows [][]any) error {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			if bs, ok := v.([]byte); ok {
+				v = string(bs)
+			}
+			k, err := json.Marshal(cols[j])
+			if err != nil {
+				return err
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Write(k)
+			b.WriteString(": ")
+			b.Write(val)
+		}
+		b.WriteString("}")
+	}
+	if len(rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
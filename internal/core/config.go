@@ -6,6 +6,9 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/cli"
 )
 
 type Config struct {
@@ -14,13 +17,78 @@ type Config struct {
 	Format       LogFormat
 	DbConnString string
 
+	// db
+	DbJournalMode string
+	DbSynchronous string
+	DbBusyTimeout time.Duration
+	DbForeignKeys bool
+	DbCacheSize   int
+	DbMaxConns    int
+	DbSlowQuery   time.Duration
+
+	// output
+	Output cli.OutputFormat
+
+	// db console
+	DbConsoleCommand string
+	DbConsoleWrite   bool
+
+	// users and orgs
+	ListAfter  int64
+	ListLimit  int
+	MemberRole string
+	OrgOwner   string
+
+	// authz
+	AuthzOrg   string
+	AuthzOwner string
+
+	// audit
+	AuditActor  string
+	AuditEntity string
+	AuditSince  time.Time
+	AuditUntil  time.Time
+	AuditRetain time.Duration
+
+	// purge
+	PurgeRetain time.Duration
+
+	// search
+	SearchKinds string
+
 	// assets
-	AssetsDir string
-	StaticDir string
+	AssetsDir    string
+	StaticDir    string
+	TemplatesDir string
 
 	// serve
-	ServeAddr string
-	ServeDev  bool
+	ServeAddr          string
+	ServeDev           bool
+	ServeDevSocket     string
+	ServeReadTimeout   time.Duration
+	ServeWriteTimeout  time.Duration
+	ServeIdleTimeout   time.Duration
+	ServeShutdownGrace time.Duration
+	ServeUserHeader    string
+
+	// dev
+	DevPoll time.Duration
+
+	// backup
+	BackupDir  string
+	BackupKeep int
+	RestoreAt  time.Time
+
+	// replicate
+	ReplicaDir      string
+	ReplicaInterval time.Duration
+	ReplicaSnapshot time.Duration
+	ReplicaRetain   time.Duration
+
+	// transfer
+	TransferFormat string
+	TransferBatch  int
+	TransferDryRun bool
 
 	// migrate
 	DbSchemaVersion SchemaVersion
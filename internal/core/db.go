@@ -1 +1,201 @@
 package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func (c Config) dbParams() url.Values {
+	params := url.Values{}
+	if c.DbJournalMode != "" {
+		params.Set("_journal_mode", strings.ToUpper(c.DbJournalMode))
+	}
+	if c.DbSynchronous != "" {
+		params.Set("_synchronous", strings.ToUpper(c.DbSynchronous))
+	}
+	if c.DbBusyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(c.DbBusyTimeout.Milliseconds(), 10))
+	}
+	if c.DbCacheSize != 0 {
+		params.Set("_cache_size", strconv.Itoa(c.DbCacheSize))
+	}
+	params.Set("_foreign_keys", strconv.FormatBool(c.DbForeignKeys))
+	return params
+}
+
+// dsn merges the configured pragmas into DbConnString. Parameters already
+// present in the connection string take precedence.
+func (c Config) dsn(params url.Values) string {
+	base, query, _ := strings.Cut(c.DbConnString, "?")
+	if existing, err := url.ParseQuery(query); err == nil {
+		for k, v := range existing {
+			params[k] = v
+		}
+	}
+	return base + "?" + params.Encode()
+}
+
+// DB routes statements between a single-connection writer pool, which
+// serializes writes and starts transactions with BEGIN IMMEDIATE, and a
+// read-only pool for concurrent reads over the same file.
+type DB struct {
+	writer *sql.DB
+	reader *sql.DB
+}
+
+// OpenDB opens the configured sqlite database with the configured pragmas and
+// pool limits applied, and verifies both pools before returning. Statements
+// are traced through log.
+func (c Config) OpenDB(ctx context.Context, log *slog.Logger) (_ *DB, err error) {
+	t := &tracer{log: log.With("component", "db"), slow: c.DbSlowQuery}
+
+	params := c.dbParams()
+	params.Set("_txlock", "immediate")
+	writer := c.open(params, t)
+	writer.SetMaxOpenConns(1)
+	writer.SetMaxIdleConns(1)
+	writer.SetConnMaxIdleTime(0)
+	writer.SetConnMaxLifetime(0)
+
+	db := &DB{writer: writer, reader: writer}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, db.Close())
+		}
+	}()
+
+	if err := writer.PingContext(ctx); err != nil {
+		return nil, err
+	}
+
+	// each connection to an in-memory database gets its own database, so
+	// reads have to share the writer's connection
+	if c.inMemory() {
+		return db, nil
+	}
+
+	params = c.dbParams()
+	params.Set("_query_only", "true")
+	db.reader = c.open(params, t)
+	if c.DbMaxConns > 0 {
+		db.reader.SetMaxOpenConns(c.DbMaxConns)
+		db.reader.SetMaxIdleConns(c.DbMaxConns)
+	}
+
+	if err := db.reader.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (c Config) open(params url.Values, t *tracer) *sql.DB {
+	return sql.OpenDB(&tracedConnector{
+		dsn:    c.dsn(params),
+		driver: &sqlite3.SQLiteDriver{},
+		tracer: t,
+	})
+}
+
+func (c Config) inMemory() bool {
+	base, query, _ := strings.Cut(c.DbConnString, "?")
+	return base == ":memory:" || base == "file::memory:" || strings.Contains(query, "mode=memory")
+}
+
+func (db *DB) Writer() *sql.DB {
+	return db.writer
+}
+
+func (db *DB) Reader() *sql.DB {
+	return db.reader
+}
+
+// InMemory reports whether db is an in-memory database, whose reads share the
+// writer's only connection.
+func (db *DB) InMemory() bool {
+	return db.reader == db.writer
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.writer.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs query on the read pool. Statements that write, including
+// INSERT ... RETURNING, must run on the writer via ExecContext or Update.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.reader.QueryContext(ctx, query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.reader.QueryRowContext(ctx, query, args...)
+}
+
+// Update runs fn in a write transaction, committing if fn returns nil.
+func (db *DB) Update(ctx context.Context, fn func(*sql.Tx) error) error {
+	return withTx(ctx, db.writer, nil, fn)
+}
+
+// View runs fn in a read-only transaction on the read pool.
+func (db *DB) View(ctx context.Context, fn func(*sql.Tx) error) error {
+	return withTx(ctx, db.reader, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func (db *DB) Close() error {
+	if db.reader == db.writer {
+		return db.writer.Close()
+	}
+	return errors.Join(db.reader.Close(), db.writer.Close())
+}
+
+func withTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RawConn runs fn with a sqlite connection from pool, for APIs such as online
+// backup that database/sql does not expose.
+func RawConn(ctx context.Context, pool *sql.DB, fn func(*sqlite3.SQLiteConn) error) (err error) {
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, conn.Close())
+	}()
+
+	return conn.Raw(func(dc any) error {
+		switch c := dc.(type) {
+		case *tracedConn:
+			return fn(c.SQLiteConn)
+		case *sqlite3.SQLiteConn:
+			return fn(c)
+		default:
+			return fmt.Errorf("unexpected driver conn %T", dc)
+		}
+	})
+}
+
+// DbPath returns the filesystem path of the configured database.
+func (c Config) DbPath() string {
+	base, _, _ := strings.Cut(c.DbConnString, "?")
+	return strings.TrimPrefix(base, "file:")
+}
@@ -0,0 +1,125 @@
+package core_test
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func TestOpenDB(t *testing.T) {
+	cfg := core.Config{
+		DbConnString:  path.Join(t.TempDir(), "test.db"),
+		DbJournalMode: "wal",
+		DbSynchronous: "normal",
+		DbBusyTimeout: 2 * time.Second,
+		DbForeignKeys: true,
+		DbCacheSize:   -4000,
+		DbMaxConns:    2,
+	}
+
+	db, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	want := map[string]string{
+		"journal_mode": "wal",
+		"synchronous":  "1",
+		"busy_timeout": "2000",
+		"foreign_keys": "1",
+		"cache_size":   "-4000",
+	}
+	got := map[string]string{}
+	for pragma := range want {
+		var v string
+		if err := db.QueryRowContext(t.Context(), "PRAGMA "+pragma).Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		got[pragma] = v
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pragma mismatch (-want +got):\n%s", diff)
+	}
+
+	if n := db.Writer().Stats().MaxOpenConnections; n != 1 {
+		t.Errorf("want writer max open conns = 1, but got %d", n)
+	}
+	if n := db.Reader().Stats().MaxOpenConnections; n != 2 {
+		t.Errorf("want reader max open conns = 2, but got %d", n)
+	}
+}
+
+func TestDBRouting(t *testing.T) {
+	cfg := core.Config{
+		DbConnString:  path.Join(t.TempDir(), "test.db"),
+		DbJournalMode: "wal",
+		DbBusyTimeout: time.Second,
+		DbMaxConns:    4,
+	}
+
+	db, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	if _, err := db.ExecContext(t.Context(), "CREATE TABLE things (id INTEGER PRIMARY KEY, name TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Update(t.Context(), func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(t.Context(), "INSERT INTO things (name) VALUES ('one'), ('two')")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	if err := db.View(t.Context(), func(tx *sql.Tx) error {
+		return tx.QueryRowContext(t.Context(), "SELECT count(*) FROM things").Scan(&n)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 rows, but got %d", n)
+	}
+
+	if _, err := db.Reader().ExecContext(t.Context(), "DELETE FROM things"); err == nil {
+		t.Error("want error writing through the read pool, but got nil")
+	}
+
+	rollback := errors.New("rollback")
+	err = db.Update(t.Context(), func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(t.Context(), "DELETE FROM things"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Errorf("want rollback error, but got %v", err)
+	}
+	if err := db.QueryRowContext(t.Context(), "SELECT count(*) FROM things").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 rows after rollback, but got %d", n)
+	}
+}
+
+func TestOpenDBInvalid(t *testing.T) {
+	cfg := core.Config{
+		DbConnString:  path.Join(t.TempDir(), "test.db"),
+		DbJournalMode: "sideways",
+	}
+
+	if _, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("want error for invalid journal mode, but got nil")
+	}
+}
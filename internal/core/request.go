@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type ctxKey int
+
+const (
+	requestIdKey ctxKey = iota
+	actorKey
+)
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+func RequestId(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIdKey).(string)
+	return id, ok && id != ""
+}
+
+func NewRequestId() string {
+	b := make([]byte, 8)
+	rand.Read(b) //nolint:errcheck
+	return hex.EncodeToString(b)
+}
+
+// WithActor records who is making changes in ctx, e.g. "user:1" or "cli:root".
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func Actor(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey).(string)
+	return actor, ok && actor != ""
+}
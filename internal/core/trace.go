@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"log/slog"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// tracer times every statement run through a traced connection. Statements
+// are logged at debug level, or at warn level once they exceed slow.
+type tracer struct {
+	log  *slog.Logger
+	slow time.Duration
+}
+
+func (t *tracer) trace(ctx context.Context, op, query string, nargs int, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	level := slog.LevelDebug
+	msg := "sql " + op
+	if t.slow > 0 && elapsed >= t.slow {
+		level = slog.LevelWarn
+		msg = "slow sql " + op
+	}
+	if !t.log.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", strings.Join(strings.Fields(query), " ")),
+		slog.Int("args", nargs),
+		slog.Duration("duration", elapsed),
+	}
+	if id, ok := RequestId(ctx); ok {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("err", err.Error()))
+	}
+	t.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+type tracedConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+	tracer *tracer
+}
+
+var _ driver.Connector = (*tracedConnector)(nil)
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn.(*sqlite3.SQLiteConn), c.tracer}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type tracedConn struct {
+	*sqlite3.SQLiteConn
+	tracer *tracer
+}
+
+var (
+	_ driver.ExecerContext      = (*tracedConn)(nil)
+	_ driver.QueryerContext     = (*tracedConn)(nil)
+	_ driver.ConnPrepareContext = (*tracedConn)(nil)
+	_ driver.ConnBeginTx        = (*tracedConn)(nil)
+	_ driver.Pinger             = (*tracedConn)(nil)
+)
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	c.tracer.trace(ctx, "exec", query, len(args), start, err)
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		c.tracer.trace(ctx, "query", query, len(args), start, err)
+		return nil, err
+	}
+	return &tracedRows{rows.(*sqlite3.SQLiteRows), ctx, c.tracer, query, len(args), start}, nil
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{stmt.(*sqlite3.SQLiteStmt), c.tracer, query}, nil
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+type tracedStmt struct {
+	*sqlite3.SQLiteStmt
+	tracer *tracer
+	query  string
+}
+
+var (
+	_ driver.StmtExecContext  = (*tracedStmt)(nil)
+	_ driver.StmtQueryContext = (*tracedStmt)(nil)
+)
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.SQLiteStmt.ExecContext(ctx, args)
+	s.tracer.trace(ctx, "exec", s.query, len(args), start, err)
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.SQLiteStmt.QueryContext(ctx, args)
+	if err != nil {
+		s.tracer.trace(ctx, "query", s.query, len(args), start, err)
+		return nil, err
+	}
+	return &tracedRows{rows.(*sqlite3.SQLiteRows), ctx, s.tracer, s.query, len(args), start}, nil
+}
+
+// tracedRows defers logging until the rows are closed, since sqlite does the
+// work of a query as its rows are stepped through.
+type tracedRows struct {
+	*sqlite3.SQLiteRows
+	ctx    context.Context
+	tracer *tracer
+	query  string
+	nargs  int
+	start  time.Time
+}
+
+func (r *tracedRows) Close() error {
+	err := r.SQLiteRows.Close()
+	r.tracer.trace(r.ctx, "query", r.query, r.nargs, r.start, err)
+	return err
+}
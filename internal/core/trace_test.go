@@ -0,0 +1,126 @@
+package core_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+func TestTrace(t *testing.T) {
+	var b strings.Builder
+	log := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	cfg := core.Config{
+		DbConnString: path.Join(t.TempDir(), "test.db"),
+		DbSlowQuery:  -1,
+	}
+	db, err := cfg.OpenDB(t.Context(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	if _, err := db.ExecContext(t.Context(), "CREATE TABLE secrets (value TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Reset()
+	ctx := core.WithRequestId(t.Context(), "req-1")
+	if _, err := db.ExecContext(ctx, "INSERT INTO secrets (value)\n\tVALUES (?)", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT value FROM secrets WHERE value = ?", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(b.String(), "hunter2") {
+		t.Errorf("want bound args redacted, but got:\n%s", b.String())
+	}
+
+	type entry struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		SQL       string `json:"sql"`
+		Args      int    `json:"args"`
+		RequestId string `json:"request_id"`
+	}
+	var got []entry
+	for line := range strings.Lines(b.String()) {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+
+	want := []entry{
+		{"DEBUG", "sql exec", "INSERT INTO secrets (value) VALUES (?)", 1, "req-1"},
+		{"DEBUG", "sql query", "SELECT value FROM secrets WHERE value = ?", 1, "req-1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("trace mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceSlow(t *testing.T) {
+	var b strings.Builder
+	log := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	cfg := core.Config{
+		DbConnString: path.Join(t.TempDir(), "test.db"),
+		DbSlowQuery:  time.Nanosecond,
+	}
+	db, err := cfg.OpenDB(t.Context(), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	if _, err := db.ExecContext(t.Context(), "CREATE TABLE secrets (value TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Reset()
+	if _, err := db.ExecContext(t.Context(), "INSERT INTO secrets (value) VALUES (?)", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	type entry struct {
+		Level    string        `json:"level"`
+		Msg      string        `json:"msg"`
+		SQL      string        `json:"sql"`
+		Args     int           `json:"args"`
+		Duration time.Duration `json:"duration"`
+	}
+	var got []entry
+	for line := range strings.Lines(b.String()) {
+		var e entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+	if len(got) == 1 && got[0].Duration <= 0 {
+		t.Errorf("want a positive duration, but got %v", got[0].Duration)
+	}
+
+	want := []entry{
+		{"WARN", "slow sql exec", "INSERT INTO secrets (value) VALUES (?)", 1, 0},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(entry{}, "Duration")); diff != "" {
+		t.Errorf("trace mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,14 @@
+// Package islands declares the props passed from Go to each client-side
+// island. Run tilde gen props after changing them to regenerate the matching
+// TypeScript types.
+package islands
+
+// All lists the props types that tilde gen props generates types for.
+var All = []any{
+	AppProps{},
+}
+
+// AppProps are the props of the App component mounted by build/main.tsx.
+type AppProps struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,25 @@
+package islands_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/islands"
+	"github.com/jonathonwebb/tilde/internal/view"
+)
+
+func TestPropsTypes(t *testing.T) {
+	want, err := os.ReadFile("../../ui/assets/props.gen.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	if err := view.TypeScript(&got, islands.All...); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), got.String()); diff != "" {
+		t.Errorf("props.gen.ts is out of date, run tilde gen props (-want +got):\n%s", diff)
+	}
+}
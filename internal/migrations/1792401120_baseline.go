@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+// the users and orgs tables predate migrations, so existing databases may
+// already have them
+var _1792401120_baseline = schema.Migration{
+	Id:   1792401120,
+	Desc: "create users and orgs",
+	Up: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, username TEXT UNIQUE NOT NULL);
+CREATE TABLE IF NOT EXISTS orgs (id INTEGER PRIMARY KEY, name TEXT UNIQUE NOT NULL);`)
+		return err
+	},
+	Down: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `DROP TABLE orgs;
+DROP TABLE users;`)
+		return err
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+var _1792402800_org_memberships = schema.Migration{
+	Id:   1792402800,
+	Desc: "create org memberships",
+	Up: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `CREATE TABLE org_memberships (org_id INTEGER NOT NULL REFERENCES orgs (id) ON DELETE CASCADE, user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')), PRIMARY KEY (org_id, user_id));
+CREATE INDEX org_memberships_user_id ON org_memberships (user_id);`)
+		return err
+	},
+	Down: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `DROP TABLE org_memberships;`)
+		return err
+	},
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+// actors and entities are not foreign keys, so events outlive what they
+// describe
+var _1792404600_audit_events = schema.Migration{
+	Id:   1792404600,
+	Desc: "create audit events",
+	Up: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `CREATE TABLE audit_events (id INTEGER PRIMARY KEY, actor TEXT NOT NULL, action TEXT NOT NULL, entity_type TEXT NOT NULL, entity_id INTEGER NOT NULL, before TEXT, after TEXT, request_id TEXT, created_at DATETIME NOT NULL);
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
+CREATE INDEX audit_events_entity ON audit_events (entity_type, entity_id);`)
+		return err
+	},
+	Down: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `DROP TABLE audit_events;`)
+		return err
+	},
+}
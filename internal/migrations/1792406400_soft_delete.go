@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+var _1792406400_soft_delete = schema.Migration{
+	Id:   1792406400,
+	Desc: "add deleted_at to users and orgs",
+	Up: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+ALTER TABLE orgs ADD COLUMN deleted_at DATETIME;`)
+		return err
+	},
+	Down: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `DELETE FROM users WHERE deleted_at IS NOT NULL;
+DELETE FROM orgs WHERE deleted_at IS NOT NULL;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE orgs DROP COLUMN deleted_at;`)
+		return err
+	},
+}
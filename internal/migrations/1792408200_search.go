@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+// The search tables use FTS5, which go-sqlite3 only compiles with the
+// sqlite_fts5 build tag. Each index row shares the rowid of the row it indexes,
+// and is rebuilt from scratch on the way up so that a down/up round trip leaves
+// it consistent with its source table.
+var _1792408200_search = schema.Migration{
+	Id:   1792408200,
+	Desc: "add full-text search over users and orgs",
+	Up: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `CREATE VIRTUAL TABLE users_search USING fts5(username, tokenize="unicode61 remove_diacritics 2");
+INSERT INTO users_search (rowid, username) SELECT id, username FROM users;
+CREATE TRIGGER users_search_insert AFTER INSERT ON users BEGIN
+  INSERT INTO users_search (rowid, username) VALUES (new.id, new.username);
+END;
+CREATE TRIGGER users_search_update AFTER UPDATE OF username ON users BEGIN
+  UPDATE users_search SET username = new.username WHERE rowid = old.id;
+END;
+CREATE TRIGGER users_search_delete AFTER DELETE ON users BEGIN
+  DELETE FROM users_search WHERE rowid = old.id;
+END;
+CREATE VIRTUAL TABLE orgs_search USING fts5(name, tokenize="unicode61 remove_diacritics 2");
+INSERT INTO orgs_search (rowid, name) SELECT id, name FROM orgs;
+CREATE TRIGGER orgs_search_insert AFTER INSERT ON orgs BEGIN
+  INSERT INTO orgs_search (rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER orgs_search_update AFTER UPDATE OF name ON orgs BEGIN
+  UPDATE orgs_search SET name = new.name WHERE rowid = old.id;
+END;
+CREATE TRIGGER orgs_search_delete AFTER DELETE ON orgs BEGIN
+  DELETE FROM orgs_search WHERE rowid = old.id;
+END;`)
+		return err
+	},
+	Down: func(ctx context.Context, db *sql.DB, log *slog.Logger) (err error) {
+		_, err = db.ExecContext(ctx, `DROP TRIGGER orgs_search_delete;
+DROP TRIGGER orgs_search_update;
+DROP TRIGGER orgs_search_insert;
+DROP TABLE orgs_search;
+DROP TRIGGER users_search_delete;
+DROP TRIGGER users_search_update;
+DROP TRIGGER users_search_insert;
+DROP TABLE users_search;`)
+		return err
+	},
+}
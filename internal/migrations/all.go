@@ -2,4 +2,10 @@ package migrations
 
 import "github.com/jonathonwebb/tilde/internal/schema"
 
-var All = []schema.Migration{}
+var All = []schema.Migration{
+	_1792401120_baseline,
+	_1792402800_org_memberships,
+	_1792404600_audit_events,
+	_1792406400_soft_delete,
+	_1792408200_search,
+}
@@ -0,0 +1,69 @@
+// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+	"errors"
+)
+
+type OrgMembership struct {
+	OrgId  int64  `json:"org_id"`
+	UserId int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+const orgMembershipColumns = "org_id, user_id, role"
+
+func scanOrgMembership(row interface{ Scan(...any) error }) (*OrgMembership, error) {
+	var m OrgMembership
+	if err := row.Scan(&m.OrgId, &m.UserId, &m.Role); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func GetOrgMembership(ctx context.Context, db DBTX, orgId int64, userId int64) (*OrgMembership, error) {
+	return scanOrgMembership(db.QueryRowContext(ctx, "SELECT "+orgMembershipColumns+" FROM org_memberships WHERE org_id = ? AND user_id = ?", orgId, userId))
+}
+
+func ListOrgMemberships(ctx context.Context, db DBTX) (ms []OrgMembership, err error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+orgMembershipColumns+" FROM org_memberships ORDER BY org_id, user_id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		m, err := scanOrgMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func InsertOrgMembership(ctx context.Context, db DBTX, m *OrgMembership) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO org_memberships (org_id, user_id, role) VALUES (?, ?, ?)", m.OrgId, m.UserId, m.Role)
+	return err
+}
+
+func UpdateOrgMembership(ctx context.Context, db DBTX, m *OrgMembership) error {
+	res, err := db.ExecContext(ctx, "UPDATE org_memberships SET role = ? WHERE org_id = ? AND user_id = ?", m.Role, m.OrgId, m.UserId)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func DeleteOrgMembership(ctx context.Context, db DBTX, orgId int64, userId int64) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM org_memberships WHERE org_id = ? AND user_id = ?", orgId, userId)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
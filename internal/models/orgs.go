@@ -0,0 +1,70 @@
+// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Org struct {
+	Id        int64               `json:"id"`
+	Name      string              `json:"name"`
+	DeletedAt sql.Null[time.Time] `json:"deleted_at"`
+}
+
+const orgColumns = "id, name, deleted_at"
+
+func scanOrg(row interface{ Scan(...any) error }) (*Org, error) {
+	var m Org
+	if err := row.Scan(&m.Id, &m.Name, &m.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func GetOrg(ctx context.Context, db DBTX, id int64) (*Org, error) {
+	return scanOrg(db.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE id = ?", id))
+}
+
+func ListOrgs(ctx context.Context, db DBTX) (ms []Org, err error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+orgColumns+" FROM orgs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		m, err := scanOrg(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func InsertOrg(ctx context.Context, db DBTX, m *Org) error {
+	return db.QueryRowContext(ctx, "INSERT INTO orgs (name, deleted_at) VALUES (?, ?) RETURNING id", m.Name, m.DeletedAt).Scan(&m.Id)
+}
+
+func UpdateOrg(ctx context.Context, db DBTX, m *Org) error {
+	res, err := db.ExecContext(ctx, "UPDATE orgs SET name = ?, deleted_at = ? WHERE id = ?", m.Name, m.DeletedAt, m.Id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func DeleteOrg(ctx context.Context, db DBTX, id int64) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM orgs WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
@@ -0,0 +1,70 @@
+// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type User struct {
+	Id        int64               `json:"id"`
+	Username  string              `json:"username"`
+	DeletedAt sql.Null[time.Time] `json:"deleted_at"`
+}
+
+const userColumns = "id, username, deleted_at"
+
+func scanUser(row interface{ Scan(...any) error }) (*User, error) {
+	var m User
+	if err := row.Scan(&m.Id, &m.Username, &m.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func GetUser(ctx context.Context, db DBTX, id int64) (*User, error) {
+	return scanUser(db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+func ListUsers(ctx context.Context, db DBTX) (ms []User, err error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		m, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func InsertUser(ctx context.Context, db DBTX, m *User) error {
+	return db.QueryRowContext(ctx, "INSERT INTO users (username, deleted_at) VALUES (?, ?) RETURNING id", m.Username, m.DeletedAt).Scan(&m.Id)
+}
+
+func UpdateUser(ctx context.Context, db DBTX, m *User) error {
+	res, err := db.ExecContext(ctx, "UPDATE users SET username = ?, deleted_at = ? WHERE id = ?", m.Username, m.DeletedAt, m.Id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func DeleteUser(ctx context.Context, db DBTX, id int64) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
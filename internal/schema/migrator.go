@@ -32,7 +32,7 @@ type SchemaStore interface {
 	commit(context.Context, int64) error
 	revert(context.Context, int64) error
 	dump(context.Context, io.Writer) error
-	load(context.Context, io.Reader) error
+	load(context.Context, io.Reader, []int64) error
 	close() error
 }
 
@@ -134,13 +134,22 @@ func NewMigration(ctx context.Context, dir, migrationName string, ts time.Time)
 
 func (m *Migrator) migrationIds() []int64 {
 	ids := make([]int64, 0, len(m.Sources))
-	for id := range m.Sources {
-		ids = append(ids, int64(id))
+	for _, src := range m.Sources {
+		ids = append(ids, int64(src.Id))
 	}
 	slices.Sort(ids)
 	return ids
 }
 
+func (m *Migrator) source(id int64) (Migration, error) {
+	for _, src := range m.Sources {
+		if int64(src.Id) == id {
+			return src, nil
+		}
+	}
+	return Migration{}, fmt.Errorf("unknown version: %d", id)
+}
+
 func (m *Migrator) Init(ctx context.Context) error {
 	return m.Store.init(ctx)
 }
@@ -148,7 +157,7 @@ func (m *Migrator) Init(ctx context.Context) error {
 func (m *Migrator) Apply(ctx context.Context, v int64) (err error) {
 	local := m.migrationIds()
 	m.Log.Debug("read local migrations", "n", len(local))
-	if !slices.Contains(local, v) {
+	if v != -1 && !slices.Contains(local, v) {
 		return fmt.Errorf("unknown version: %d", v)
 	}
 
@@ -185,8 +194,11 @@ func (m *Migrator) Apply(ctx context.Context, v int64) (err error) {
 	if latest < v {
 		// migrate up
 		for _, id := range local {
-			if id > latest {
-				src := m.Sources[uint64(id)]
+			if id > latest && id <= v {
+				src, err := m.source(id)
+				if err != nil {
+					return err
+				}
 				if err := src.Up(ctx, m.Store.db(), m.Log); err != nil {
 					shouldRelease = false
 					return err
@@ -199,10 +211,13 @@ func (m *Migrator) Apply(ctx context.Context, v int64) (err error) {
 		}
 	} else {
 		// migrate down
-		for i := len(remote) - 1; i >= 0; i++ {
+		for i := len(remote) - 1; i >= 0; i-- {
 			id := remote[i]
 			if id > v {
-				src := m.Sources[uint64(id)]
+				src, err := m.source(id)
+				if err != nil {
+					return err
+				}
 				if err := src.Down(ctx, m.Store.db(), m.Log); err != nil {
 					shouldRelease = false
 					return err
@@ -231,8 +246,42 @@ func (m *Migrator) ApplyInitial(ctx context.Context) error {
 	return m.Apply(ctx, -1)
 }
 
-func (m *Migrator) Load(ctx context.Context, r io.Reader) error {
-	return m.Store.load(ctx, strings.NewReader(Schema))
+// CheckLatest returns an error unless the latest local migration is the last
+// one applied to the store.
+func (m *Migrator) CheckLatest(ctx context.Context) error {
+	var want int64 = -1
+	if local := m.migrationIds(); len(local) > 0 {
+		want = local[len(local)-1]
+	}
+
+	remote, err := m.Store.state(ctx)
+	if err != nil {
+		return fmt.Errorf("get store state: %v", err)
+	}
+	var latest int64 = -1
+	if len(remote) > 0 {
+		latest = remote[len(remote)-1]
+	}
+
+	if latest != want {
+		return fmt.Errorf("schema version %d does not match latest version %d, run tilde migrate", latest, want)
+	}
+	return nil
+}
+
+// Load applies the schema read from r, dumped at version, and records every
+// migration up to version as applied, so the store is as if they had run.
+func (m *Migrator) Load(ctx context.Context, r io.Reader, version int64) error {
+	var ids []int64
+	for _, id := range m.migrationIds() {
+		if id <= version {
+			ids = append(ids, id)
+		}
+	}
+	if version != -1 && !slices.Contains(ids, version) {
+		return fmt.Errorf("unknown version: %d", version)
+	}
+	return m.Store.load(ctx, r, ids)
 }
 
 var schemaTmpl = template.Must(template.New("schema").Parse(`package schema
@@ -240,7 +289,8 @@ var schemaTmpl = template.Must(template.New("schema").Parse(`package schema
 const (
 	SchemaVersion = {{.SchemaVersion}}
 	Schema        = ` + "`{{.Schema}}`" + `
-)`,
+)
+`,
 ))
 
 func (m *Migrator) Dump(ctx context.Context, dir string, w io.Writer) (err error) {
@@ -259,11 +309,11 @@ func (m *Migrator) Dump(ctx context.Context, dir string, w io.Writer) (err error
 	}
 
 	p := path.Join(dir, "schema.go")
-	f, err := os.Open(p)
+	f, err := os.Create(p)
 	if err != nil {
 		return err
 	}
-	defer func() { err = errors.Join(f.Close()) }()
+	defer func() { err = errors.Join(err, f.Close()) }()
 
 	return schemaTmpl.Execute(f, struct {
 		SchemaVersion int64
@@ -0,0 +1,340 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+var (
+	modelsDbTmpl = template.Must(template.New("modelsDb").Parse(`// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx.
+type DBTX interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+`))
+	modelTmpl = template.Must(template.New("model").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(`// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+{{- if .Sql }}
+	"database/sql"
+{{- end }}
+	"errors"
+{{- if .Time }}
+	"time"
+{{- end }}
+)
+
+type {{.Type}} struct {
+{{- range .Fields }}
+	{{.Name}} {{.Type}} ` + "`json:\"{{.Column}}\"`" + `
+{{- end }}
+}
+
+const {{.Unexported}}Columns = "{{join .Columns ", "}}"
+
+func scan{{.Type}}(row interface{ Scan(...any) error }) (*{{.Type}}, error) {
+	var m {{.Type}}
+	if err := row.Scan({{range $i, $f := .Fields}}{{if $i}}, {{end}}&m.{{$f.Name}}{{end}}); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+{{- if .Key }}
+
+func Get{{.Type}}(ctx context.Context, db DBTX{{range .Key}}, {{.Param}} {{.Type}}{{end}}) (*{{.Type}}, error) {
+	return scan{{.Type}}(db.QueryRowContext(ctx, "SELECT "+{{.Unexported}}Columns+" FROM {{.Table}} WHERE {{.KeyWhere}}"{{range .Key}}, {{.Param}}{{end}}))
+}
+{{- end }}
+
+func List{{.Plural}}(ctx context.Context, db DBTX) (ms []{{.Type}}, err error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+{{.Unexported}}Columns+" FROM {{.Table}}{{if .Key}} ORDER BY {{.KeyOrder}}{{end}}")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		m, err := scan{{.Type}}(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func Insert{{.Type}}(ctx context.Context, db DBTX, m *{{.Type}}) error {
+{{- if .Returning }}
+	return db.QueryRowContext(ctx, "{{.InsertSQL}} RETURNING {{join .ReturningColumns ", "}}"{{range .Inserted}}, m.{{.Name}}{{end}}).Scan({{range $i, $f := .Returning}}{{if $i}}, {{end}}&m.{{$f.Name}}{{end}})
+{{- else }}
+	_, err := db.ExecContext(ctx, "{{.InsertSQL}}"{{range .Inserted}}, m.{{.Name}}{{end}})
+	return err
+{{- end }}
+}
+{{- if .Key }}
+{{- if .Updated }}
+
+func Update{{.Type}}(ctx context.Context, db DBTX, m *{{.Type}}) error {
+	res, err := db.ExecContext(ctx, "UPDATE {{.Table}} SET {{.UpdateSet}} WHERE {{.KeyWhere}}"{{range .Updated}}, m.{{.Name}}{{end}}{{range .Key}}, m.{{.Name}}{{end}})
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+{{- end }}
+
+func Delete{{.Type}}(ctx context.Context, db DBTX{{range .Key}}, {{.Param}} {{.Type}}{{end}}) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM {{.Table}} WHERE {{.KeyWhere}}"{{range .Key}}, {{.Param}}{{end}})
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+{{- end }}
+`))
+	modelsAffectedSrc = `
+// affected reports sql.ErrNoRows when a keyed write matched nothing.
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+`
+)
+
+type modelField struct {
+	Name, Param, Type, Column string
+}
+
+type model struct {
+	Table, Type, Plural, Unexported string
+	Sql, Time                       bool
+	Fields, Key                     []modelField
+	Inserted, Returning, Updated    []modelField
+}
+
+func (m model) Columns() []string {
+	return fieldColumns(m.Fields)
+}
+
+func (m model) ReturningColumns() []string {
+	return fieldColumns(m.Returning)
+}
+
+func (m model) InsertSQL() string {
+	if len(m.Inserted) == 0 {
+		return fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", m.Table)
+	}
+	params := strings.TrimSuffix(strings.Repeat("?, ", len(m.Inserted)), ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", m.Table, strings.Join(fieldColumns(m.Inserted), ", "), params)
+}
+
+func (m model) KeyWhere() string {
+	return assignments(m.Key, " AND ")
+}
+
+func (m model) KeyOrder() string {
+	return strings.Join(fieldColumns(m.Key), ", ")
+}
+
+func (m model) UpdateSet() string {
+	return assignments(m.Updated, ", ")
+}
+
+func fieldColumns(fields []modelField) []string {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = f.Column
+	}
+	return cols
+}
+
+func assignments(fields []modelField, sep string) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Column + " = ?"
+	}
+	return strings.Join(parts, sep)
+}
+
+// NewModels writes a Go model file for each named table in db to dir. With no
+// names, every table except the schema bookkeeping tables is generated.
+func NewModels(ctx context.Context, dir string, db *sql.DB, names ...string) error {
+	tables, err := Tables(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var selected []Table
+	for _, t := range tables {
+		if len(names) == 0 && !strings.HasPrefix(t.Name, "schema_") || slices.Contains(names, t.Name) {
+			selected = append(selected, t)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(selected, func(t Table) bool { return t.Name == name }) {
+			return fmt.Errorf("unknown table: %s", name)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := modelsDbTmpl.Execute(&b, nil); err != nil {
+		return err
+	}
+	b.WriteString(modelsAffectedSrc)
+	if err := writeSource(path.Join(dir, "db.go"), b.Bytes()); err != nil {
+		return err
+	}
+
+	for _, t := range selected {
+		b.Reset()
+		if err := modelTmpl.Execute(&b, newModel(t)); err != nil {
+			return err
+		}
+		if err := writeSource(path.Join(dir, t.Name+".go"), b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSource(p string, src []byte) error {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("format %s: %v", p, err)
+	}
+	return os.WriteFile(p, formatted, 0644)
+}
+
+func newModel(t Table) model {
+	typeName := singular(goName(t.Name))
+	m := model{
+		Table:      t.Name,
+		Type:       typeName,
+		Plural:     goName(t.Name),
+		Unexported: strings.ToLower(typeName[:1]) + typeName[1:],
+	}
+
+	pk := t.PrimaryKey()
+	for _, c := range t.Columns {
+		notNull := c.NotNull || c.PrimaryKey > 0
+		f := modelField{
+			Name:   goName(c.Name),
+			Param:  paramName(c.Name),
+			Type:   goType(c.Type, notNull),
+			Column: c.Name,
+		}
+		if strings.Contains(f.Type, "sql.") {
+			m.Sql = true
+		}
+		if strings.Contains(f.Type, "time.Time") {
+			m.Time = true
+		}
+		m.Fields = append(m.Fields, f)
+
+		switch {
+		case t.RowId(c):
+			m.Returning = append(m.Returning, f)
+		default:
+			m.Inserted = append(m.Inserted, f)
+		}
+		if c.PrimaryKey == 0 {
+			m.Updated = append(m.Updated, f)
+		}
+	}
+	for _, c := range pk {
+		i := slices.IndexFunc(m.Fields, func(f modelField) bool { return f.Column == c.Name })
+		m.Key = append(m.Key, m.Fields[i])
+	}
+
+	return m
+}
+
+func goType(declared string, notNull bool) string {
+	upper := strings.ToUpper(declared)
+	var t string
+	switch {
+	case strings.Contains(upper, "DATE"), strings.Contains(upper, "TIME"):
+		t = "time.Time"
+	case strings.Contains(upper, "BOOL"):
+		t = "bool"
+	case strings.Contains(upper, "INT"):
+		t = "int64"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		t = "string"
+	case upper == "", strings.Contains(upper, "BLOB"):
+		return "[]byte"
+	default:
+		t = "float64"
+	}
+	if notNull {
+		return t
+	}
+	return fmt.Sprintf("sql.Null[%s]", t)
+}
+
+func goName(name string) string {
+	var b strings.Builder
+	for part := range strings.SplitSeq(name, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+func paramName(name string) string {
+	n := goName(name)
+	n = strings.ToLower(n[:1]) + n[1:]
+	if token.IsKeyword(n) || n == "ctx" || n == "db" {
+		return "_" + n
+	}
+	return n
+}
+
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ss"):
+		return name
+	default:
+		return strings.TrimSuffix(name, "s")
+	}
+}
@@ -0,0 +1,131 @@
+package schema_test
+
+import (
+	"database/sql"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/schema"
+)
+
+func TestNewModels(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close() //nolint:errcheck
+
+	if _, err := db.ExecContext(t.Context(), `CREATE TABLE schema_lock (id INTEGER PRIMARY KEY);
+CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL, due_at DATETIME);`); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("all tables", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := schema.NewModels(t.Context(), dir, db); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		want := []string{"db.go", "notes.go"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("generated files mismatch (-want +got):\n%s", diff)
+		}
+
+		d, err := os.ReadFile(path.Join(dir, "notes.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(wantNotes, string(d)); diff != "" {
+			t.Errorf("model mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		err := schema.NewModels(t.Context(), t.TempDir(), db, "missing")
+		if err == nil || err.Error() != "unknown table: missing" {
+			t.Errorf("want unknown table error, but got %v", err)
+		}
+	})
+}
+
+const wantNotes = `// Code generated by tilde gen models. DO NOT EDIT.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Note struct {
+	Id    int64               ` + "`json:\"id\"`" + `
+	Body  string              ` + "`json:\"body\"`" + `
+	DueAt sql.Null[time.Time] ` + "`json:\"due_at\"`" + `
+}
+
+const noteColumns = "id, body, due_at"
+
+func scanNote(row interface{ Scan(...any) error }) (*Note, error) {
+	var m Note
+	if err := row.Scan(&m.Id, &m.Body, &m.DueAt); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func GetNote(ctx context.Context, db DBTX, id int64) (*Note, error) {
+	return scanNote(db.QueryRowContext(ctx, "SELECT "+noteColumns+" FROM notes WHERE id = ?", id))
+}
+
+func ListNotes(ctx context.Context, db DBTX) (ms []Note, err error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+noteColumns+" FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		m, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func InsertNote(ctx context.Context, db DBTX, m *Note) error {
+	return db.QueryRowContext(ctx, "INSERT INTO notes (body, due_at) VALUES (?, ?) RETURNING id", m.Body, m.DueAt).Scan(&m.Id)
+}
+
+func UpdateNote(ctx context.Context, db DBTX, m *Note) error {
+	res, err := db.ExecContext(ctx, "UPDATE notes SET body = ?, due_at = ? WHERE id = ?", m.Body, m.DueAt, m.Id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func DeleteNote(ctx context.Context, db DBTX, id int64) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM notes WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+`
@@ -1,9 +1,34 @@
 package schema
 
 const (
-	SchemaVersion = 2
+	SchemaVersion = 1792408200
 	Schema        = `CREATE TABLE schema_lock (id INTEGER PRIMARY KEY);
 CREATE TABLE schema_migrations (id INTEGER PRIMARY KEY, version_id INTEGER UNIQUE NOT NULL, applied_at DATETIME NOT NULL DEFAULT (datetime('now')));
-CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT UNIQUE NOT NULL);
-CREATE TABLE orgs (id INTEGER PRIMARY KEY, name TEXT UNIQUE NOT NULL);`
+CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT UNIQUE NOT NULL, deleted_at DATETIME);
+CREATE TABLE orgs (id INTEGER PRIMARY KEY, name TEXT UNIQUE NOT NULL, deleted_at DATETIME);
+CREATE TABLE org_memberships (org_id INTEGER NOT NULL REFERENCES orgs (id) ON DELETE CASCADE, user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')), PRIMARY KEY (org_id, user_id));
+CREATE INDEX org_memberships_user_id ON org_memberships (user_id);
+CREATE TABLE audit_events (id INTEGER PRIMARY KEY, actor TEXT NOT NULL, action TEXT NOT NULL, entity_type TEXT NOT NULL, entity_id INTEGER NOT NULL, before TEXT, after TEXT, request_id TEXT, created_at DATETIME NOT NULL);
+CREATE INDEX audit_events_created_at ON audit_events (created_at);
+CREATE INDEX audit_events_entity ON audit_events (entity_type, entity_id);
+CREATE VIRTUAL TABLE users_search USING fts5(username, tokenize="unicode61 remove_diacritics 2");
+CREATE TRIGGER users_search_insert AFTER INSERT ON users BEGIN
+  INSERT INTO users_search (rowid, username) VALUES (new.id, new.username);
+END;
+CREATE TRIGGER users_search_update AFTER UPDATE OF username ON users BEGIN
+  UPDATE users_search SET username = new.username WHERE rowid = old.id;
+END;
+CREATE TRIGGER users_search_delete AFTER DELETE ON users BEGIN
+  DELETE FROM users_search WHERE rowid = old.id;
+END;
+CREATE VIRTUAL TABLE orgs_search USING fts5(name, tokenize="unicode61 remove_diacritics 2");
+CREATE TRIGGER orgs_search_insert AFTER INSERT ON orgs BEGIN
+  INSERT INTO orgs_search (rowid, name) VALUES (new.id, new.name);
+END;
+CREATE TRIGGER orgs_search_update AFTER UPDATE OF name ON orgs BEGIN
+  UPDATE orgs_search SET name = new.name WHERE rowid = old.id;
+END;
+CREATE TRIGGER orgs_search_delete AFTER DELETE ON orgs BEGIN
+  DELETE FROM orgs_search WHERE rowid = old.id;
+END;`
 )
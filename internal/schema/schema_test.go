@@ -1,60 +1,163 @@
 package schema_test
 
 import (
+	"database/sql"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/migrations"
+	"github.com/jonathonwebb/tilde/internal/schema"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// func TestSchema(t *testing.T) {
-// 	testDb, err := sql.Open("sqlite3", ":memory:")
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	defer testDb.Close()
-
-// 	m := schema.Migrator{
-// 		Store:   schema.NewSqlite3SchemaStore(testDb),
-// 		Log:     *slog.Default(),
-// 		Sources: migrations.All,
-// 	}
-// 	err = m.ApplyLatest(t.Context())
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-
-// 	var b strings.Builder
-// 	var stmts []string
-// 	rows, err := testDb.Query("SELECT sql FROM sqlite_schema")
-// 	if err != nil {
-// 		t.Fatal(err)
-// 	}
-// 	defer rows.Close()
-
-// 	for rows.Next() {
-// 		var stmt sql.NullString
-// 		if err := rows.Scan(&stmt); err != nil {
-// 			t.Fatal(err)
-// 		}
-// 		if stmt.Valid {
-// 			el := stmt.String
-// 			if !strings.HasSuffix(b.String(), ";") {
-// 				el += ";"
-// 			}
-// 			stmts = append(stmts, el)
-// 		}
-// 	}
-
-// 	want := db.Schema
-// 	got := strings.Join(stmts, "\n")
-
-// 	if diff := cmp.Diff(want, got); diff != "" {
-// 		t.Errorf("schema mismatch (-want +got):\n%s", diff)
-// 	}
-// }
-
-// func TestSchemaVersion(t *testing.T) {
-// 	var ids []uint64
-// 	for id, _ := range db.Migrations {
-// 		ids = append(ids, id)
-// 	}
-// 	slices.Sort(ids)
-// }
+func TestSchema(t *testing.T) {
+	m, _ := newMigrator(t)
+	if err := m.ApplyLatest(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := m.Dump(t.Context(), dir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile("schema.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path.Join(dir, "schema.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("schema.go is out of date with migrations (-want +got):\n%s", diff)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	m, db := newMigrator(t)
+	if err := m.ApplyLatest(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyInitial(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"schema_lock", "schema_migrations"}
+	if diff := cmp.Diff(want, tableNames(t, db)); diff != "" {
+		t.Errorf("tables mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestSearchRoundTrip checks that migrating the search index down and up again
+// picks up changes made while it was gone.
+func TestSearchRoundTrip(t *testing.T) {
+	m, db := newMigrator(t)
+	ctx := t.Context()
+	if err := m.ApplyLatest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (username) VALUES ('alice'), ('bob')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Apply(ctx, 1792406400); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE users SET username = 'carol' WHERE username = 'bob'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ApplyLatest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO users (username) VALUES ('dave')"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT username FROM users_search ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close() //nolint:errcheck
+	var got []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"alice", "carol", "dave"}, got); diff != "" {
+		t.Errorf("search index mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckLatest(t *testing.T) {
+	m, _ := newMigrator(t)
+	if err := m.CheckLatest(t.Context()); err == nil {
+		t.Error("want error before migrating, but got nil")
+	}
+	if err := m.ApplyLatest(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CheckLatest(t.Context()); err != nil {
+		t.Errorf("want nil after migrating, but got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	m, db := newMigrator(t)
+	if err := m.Load(t.Context(), strings.NewReader(schema.Schema), schema.SchemaVersion); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CheckLatest(t.Context()); err != nil {
+		t.Errorf("want nil after loading, but got %v", err)
+	}
+
+	want := []string{"audit_events", "org_memberships", "orgs", "schema_lock", "schema_migrations", "users"}
+	if diff := cmp.Diff(want, tableNames(t, db)); diff != "" {
+		t.Errorf("tables mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func newMigrator(t testing.TB) (*schema.Migrator, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path.Join(t.TempDir(), "data.db")+"?_foreign_keys=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	log := slog.New(slog.DiscardHandler)
+	return &schema.Migrator{
+		Store:   schema.NewSqlite3SchemaStore(db, log),
+		Log:     log,
+		Sources: migrations.All,
+	}, db
+}
+
+func tableNames(t testing.TB, db *sql.DB) []string {
+	t.Helper()
+
+	tables, err := schema.Tables(t.Context(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	return names
+}
@@ -30,10 +30,10 @@ func (s *Sqlite3SchemaStore) db() *sql.DB {
 
 func (s *Sqlite3SchemaStore) init(ctx context.Context) error {
 	if err := s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
-		if _, err := s.instance.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY)"); err != nil {
+		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_lock (id INTEGER PRIMARY KEY)"); err != nil {
 			return err
 		}
-		if _, err := s.instance.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (id INTEGER PRIMARY KEY, version_id INTEGER UNIQUE NOT NULL, applied_at DATETIME NOT NULL DEFAULT (datetime('now')))"); err != nil {
+		if _, err := tx.ExecContext(tCtx, "CREATE TABLE IF NOT EXISTS schema_migrations (id INTEGER PRIMARY KEY, version_id INTEGER UNIQUE NOT NULL, applied_at DATETIME NOT NULL DEFAULT (datetime('now')))"); err != nil {
 			return err
 		}
 		return nil
@@ -79,11 +79,19 @@ func (s *Sqlite3SchemaStore) release(ctx context.Context) error {
 }
 
 func (s *Sqlite3SchemaStore) state(ctx context.Context) (ids []int64, err error) {
+	var exists bool
+	if err := s.db().QueryRowContext(ctx, "SELECT count(*) > 0 FROM sqlite_schema WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
 	rows, err := s.db().QueryContext(ctx, `SELECT version_id FROM schema_migrations`)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { err = errors.Join(rows.Close()) }()
+	defer func() { err = errors.Join(err, rows.Close()) }()
 
 	for rows.Next() {
 		var vid int64
@@ -127,13 +135,15 @@ func (s *Sqlite3SchemaStore) revert(ctx context.Context, id int64) error {
 	return nil
 }
 
+// dump writes the sql of every schema object except the shadow tables that back
+// virtual tables, which sqlite creates along with them.
 func (s *Sqlite3SchemaStore) dump(ctx context.Context, w io.Writer) (err error) {
 	var stmts []string
-	rows, err := s.db().QueryContext(ctx, "SELECT sql FROM sqlite_schema")
+	rows, err := s.db().QueryContext(ctx, "SELECT sql FROM sqlite_schema WHERE name NOT IN (SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'shadow')")
 	if err != nil {
 		return err
 	}
-	defer func() { err = errors.Join(rows.Close()) }()
+	defer func() { err = errors.Join(err, rows.Close()) }()
 
 	for rows.Next() {
 		var stmt sql.NullString
@@ -157,14 +167,26 @@ func (s *Sqlite3SchemaStore) dump(ctx context.Context, w io.Writer) (err error)
 	return nil
 }
 
-func (s *Sqlite3SchemaStore) load(ctx context.Context, r io.Reader) error {
-	stmts := strings.SplitSeq(Schema, ";")
-	for stmt := range stmts {
-		if _, err := s.db().ExecContext(ctx, stmt); err != nil {
+// load executes the schema read from r as a single script, since splitting it
+// into statements would break any trigger bodies, and records the migration
+// ids in the same transaction.
+func (s *Sqlite3SchemaStore) load(ctx context.Context, r io.Reader, ids []int64) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.withTx(ctx, func(tCtx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(tCtx, string(b)); err != nil {
 			return err
 		}
-	}
-	return nil
+		for _, id := range ids {
+			if _, err := tx.ExecContext(tCtx, "INSERT INTO schema_migrations (version_id) VALUES (?)", id); err != nil {
+				return err
+			}
+		}
+		s.log.Debug("loaded schema", "migrations", len(ids))
+		return nil
+	})
 }
 
 func (s *Sqlite3SchemaStore) close() error {
@@ -177,7 +199,9 @@ func (s *Sqlite3SchemaStore) withTx(ctx context.Context, fn func(context.Context
 		return err
 	}
 	defer func() {
-		err = errors.Join(err, tx.Rollback())
+		if rbErr := tx.Rollback(); !errors.Is(rbErr, sql.ErrTxDone) {
+			err = errors.Join(err, rbErr)
+		}
 	}()
 
 	err = fn(ctx, tx)
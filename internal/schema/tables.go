@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type Table struct {
+	Name    string
+	Columns []Column
+	Unique  [][]string
+}
+
+type Column struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey int
+	Default    sql.NullString
+}
+
+// PrimaryKey returns the table's primary key columns in key order.
+func (t Table) PrimaryKey() []Column {
+	var pk []Column
+	for i := 1; ; i++ {
+		found := false
+		for _, c := range t.Columns {
+			if c.PrimaryKey == i {
+				pk = append(pk, c)
+				found = true
+			}
+		}
+		if !found {
+			return pk
+		}
+	}
+}
+
+func (t Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// RowId reports whether the column is an alias for the table's rowid, which
+// sqlite assigns on insert when no value is given.
+func (t Table) RowId(c Column) bool {
+	pk := t.PrimaryKey()
+	return len(pk) == 1 && pk[0].Name == c.Name && strings.EqualFold(c.Type, "INTEGER")
+}
+
+// Tables reads the definition of every ordinary user table, leaving out virtual
+// tables and the shadow tables that back them.
+func Tables(ctx context.Context, db *sql.DB) (tables []Table, err error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range tables {
+		cols, err := columns(ctx, db, tables[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		tables[i].Columns = cols
+
+		unique, err := uniqueKeys(ctx, db, tables[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		tables[i].Unique = unique
+	}
+
+	return tables, nil
+}
+
+func columns(ctx context.Context, db *sql.DB, table string) (cols []Column, err error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", pk, dflt_value FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.Type, &c.NotNull, &c.PrimaryKey, &c.Default); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// uniqueKeys returns the columns of each unique constraint on table other than
+// the primary key.
+func uniqueKeys(ctx context.Context, db *sql.DB, table string) (keys [][]string, err error) {
+	rows, err := db.QueryContext(ctx, `SELECT il.name, ii.name FROM pragma_index_list(?) AS il, pragma_index_info(il.name) AS ii WHERE il."unique" = 1 AND il.origin != 'pk' ORDER BY il.name, ii.seqno`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	var last string
+	for rows.Next() {
+		var index, col string
+		if err := rows.Scan(&index, &col); err != nil {
+			return nil, err
+		}
+		if index != last || len(keys) == 0 {
+			keys = append(keys, nil)
+			last = index
+		}
+		keys[len(keys)-1] = append(keys[len(keys)-1], col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
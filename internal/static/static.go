@@ -0,0 +1,156 @@
+// Package static serves the public asset directory.
+package static
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hashed matches the names esbuild gives build outputs with [name]-[hash],
+// whose contents never change under the same name.
+var hashed = regexp.MustCompile(`^build/.+-[A-Z2-7]{8}\.[^/]+$`)
+
+const (
+	immutable  = "public, max-age=31536000, immutable"
+	revalidate = "no-cache"
+)
+
+// encodings are the precompressed siblings served in place of a file, in order
+// of preference, keyed by their file extension.
+var encodings = []struct{ name, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Handler serves the files in fsys, which should be mounted with the URL
+// prefix stripped. Hashed build outputs are cached forever; everything else
+// is revalidated with its ETag and modification time. Directories and hidden
+// files are not served.
+func Handler(fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if !fs.ValidPath(name) || name == "." || hidden(name) {
+			http.NotFound(w, r)
+			return
+		}
+
+		f, info, err := open(fsys, name)
+		if err != nil {
+			serveErr(w, r, err)
+			return
+		}
+		defer f.Close() //nolint:errcheck
+
+		h := w.Header()
+		if hashed.MatchString(name) {
+			h.Set("Cache-Control", immutable)
+		} else {
+			h.Set("Cache-Control", revalidate)
+		}
+
+		// Serve a compressed sibling if the client takes it, tagging the
+		// response so that caches keep each encoding apart.
+		tag := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+		for _, enc := range encodings {
+			cf, cinfo, err := open(fsys, name+enc.ext)
+			if err != nil {
+				continue
+			}
+			h.Set("Vary", "Accept-Encoding")
+			if !accepts(r.Header.Get("Accept-Encoding"), enc.name) {
+				cf.Close() //nolint:errcheck
+				continue
+			}
+			defer cf.Close() //nolint:errcheck
+			h.Set("Content-Encoding", enc.name)
+			f, info = cf, cinfo
+			tag += "-" + enc.name
+			break
+		}
+		h.Set("ETag", `"`+tag+`"`)
+
+		// The type comes from the requested name, since sniffing would see
+		// compressed bytes.
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if ctype == "" && h.Get("Content-Encoding") != "" {
+			ctype = "application/octet-stream"
+		}
+		if ctype != "" {
+			h.Set("Content-Type", ctype)
+		}
+
+		content, ok := f.(io.ReadSeeker)
+		if !ok {
+			http.Error(w, "file is not seekable", http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, name, info.ModTime(), content)
+	})
+}
+
+// open opens a regular file, treating directories as missing.
+func open(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, errors.Join(err, f.Close())
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, errors.Join(fs.ErrNotExist, f.Close())
+	}
+	return f, info, nil
+}
+
+func hidden(name string) bool {
+	for _, seg := range strings.Split(name, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func serveErr(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// accepts reports whether an Accept-Encoding header allows encoding, either by
+// name or with a wildcard, with a non-zero quality.
+func accepts(header, encoding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch {
+		case strings.EqualFold(name, encoding):
+			return q > 0
+		case name == "*":
+			wildcard = q > 0
+		}
+	}
+	return wildcard
+}
@@ -0,0 +1,152 @@
+package static_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/static"
+)
+
+var modTime = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func testFS() fstest.MapFS {
+	file := func(data string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data), ModTime: modTime}
+	}
+	return fstest.MapFS{
+		"build/main-ABC234XY.js":    file("console.log(1)"),
+		"build/main-ABC234XY.js.gz": file("gzipped"),
+		"build/main-ABC234XY.js.br": file("brotli"),
+		"build/meta.json":           file("{}"),
+		"build/.meta.json":          file("{}"),
+		"robots.txt":                file("User-agent: *"),
+		"app.css":                   file("body {}"),
+		".env":                      file("SECRET=1"),
+	}
+}
+
+type response struct {
+	Status                                    int
+	Body                                      string
+	CacheControl, ContentType, Encoding, Vary string
+}
+
+func serve(t testing.TB, path string, header map[string]string) (response, http.Header) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	static.Handler(testFS()).ServeHTTP(w, r)
+
+	h := w.Result().Header
+	return response{
+		Status:       w.Code,
+		Body:         w.Body.String(),
+		CacheControl: h.Get("Cache-Control"),
+		ContentType:  h.Get("Content-Type"),
+		Encoding:     h.Get("Content-Encoding"),
+		Vary:         h.Get("Vary"),
+	}, h
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		want   response
+	}{
+		{
+			name: "hashed build file",
+			path: "/build/main-ABC234XY.js",
+			want: response{Status: 200, Body: "console.log(1)", CacheControl: "public, max-age=31536000, immutable", ContentType: "text/javascript; charset=utf-8", Vary: "Accept-Encoding"},
+		},
+		{
+			name:   "prefers brotli",
+			path:   "/build/main-ABC234XY.js",
+			header: map[string]string{"Accept-Encoding": "gzip, deflate, br"},
+			want:   response{Status: 200, Body: "brotli", CacheControl: "public, max-age=31536000, immutable", ContentType: "text/javascript; charset=utf-8", Encoding: "br", Vary: "Accept-Encoding"},
+		},
+		{
+			name:   "falls back to gzip",
+			path:   "/build/main-ABC234XY.js",
+			header: map[string]string{"Accept-Encoding": "gzip, br;q=0"},
+			want:   response{Status: 200, Body: "gzipped", CacheControl: "public, max-age=31536000, immutable", ContentType: "text/javascript; charset=utf-8", Encoding: "gzip", Vary: "Accept-Encoding"},
+		},
+		{
+			name: "unhashed build file",
+			path: "/build/meta.json",
+			want: response{Status: 200, Body: "{}", CacheControl: "no-cache", ContentType: "application/json"},
+		},
+		{
+			name: "other file",
+			path: "/app.css",
+			want: response{Status: 200, Body: "body {}", CacheControl: "no-cache", ContentType: "text/css; charset=utf-8"},
+		},
+		{
+			name:   "range",
+			path:   "/robots.txt",
+			header: map[string]string{"Range": "bytes=0-9"},
+			want:   response{Status: 206, Body: "User-agent", CacheControl: "no-cache", ContentType: "text/plain; charset=utf-8"},
+		},
+		{
+			name:   "not modified since",
+			path:   "/robots.txt",
+			header: map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			want:   response{Status: 304, CacheControl: "no-cache"},
+		},
+		{
+			name: "missing",
+			path: "/nope.js",
+			want: response{Status: 404, Body: "404 page not found\n", ContentType: "text/plain; charset=utf-8"},
+		},
+		{
+			name: "directory",
+			path: "/build/",
+			want: response{Status: 404, Body: "404 page not found\n", ContentType: "text/plain; charset=utf-8"},
+		},
+		{
+			name: "hidden",
+			path: "/.env",
+			want: response{Status: 404, Body: "404 page not found\n", ContentType: "text/plain; charset=utf-8"},
+		},
+		{
+			name: "hidden build file",
+			path: "/build/.meta.json",
+			want: response{Status: 404, Body: "404 page not found\n", ContentType: "text/plain; charset=utf-8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := serve(t, tt.path, tt.header)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandlerETag(t *testing.T) {
+	_, h := serve(t, "/robots.txt", nil)
+	etag := h.Get("ETag")
+	if etag == "" {
+		t.Fatal("want ETag, but got none")
+	}
+	got, _ := serve(t, "/robots.txt", map[string]string{"If-None-Match": etag})
+	if got.Status != http.StatusNotModified {
+		t.Errorf("want status %d revalidating, but got %d", http.StatusNotModified, got.Status)
+	}
+
+	_, h = serve(t, "/build/main-ABC234XY.js", nil)
+	identity := h.Get("ETag")
+	_, h = serve(t, "/build/main-ABC234XY.js", map[string]string{"Accept-Encoding": "gzip"})
+	if gz := h.Get("ETag"); gz == identity || gz == "" {
+		t.Errorf("want an ETag for the gzip encoding distinct from %q, but got %q", identity, gz)
+	}
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+// audit records a change to an entity in the transaction making it. The actor
+// and request id are taken from ctx, and before and after are stored as JSON.
+func audit(ctx context.Context, tx *sql.Tx, action, entityType string, entityId int64, before, after any) error {
+	e := &models.AuditEvent{
+		Actor:      "unknown",
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if actor, ok := core.Actor(ctx); ok {
+		e.Actor = actor
+	}
+	if id, ok := core.RequestId(ctx); ok {
+		e.RequestId = sql.Null[string]{V: id, Valid: true}
+	}
+
+	var err error
+	if e.Before, err = jsonValue(before); err != nil {
+		return err
+	}
+	if e.After, err = jsonValue(after); err != nil {
+		return err
+	}
+	return models.InsertAuditEvent(ctx, tx, e)
+}
+
+func jsonValue(v any) (sql.Null[string], error) {
+	if v == nil {
+		return sql.Null[string]{}, nil
+	}
+	b, err := json.Marshal(flatten(v))
+	if err != nil {
+		return sql.Null[string]{}, err
+	}
+	return sql.Null[string]{V: string(b), Valid: true}, nil
+}
+
+// DefaultAuditRetain is how long audit events are kept by default, 90 days.
+const DefaultAuditRetain = 90 * 24 * time.Hour
+
+// flatten converts a model struct to a map keyed by column name, replacing
+// nullable fields with their values, which would otherwise be encoded as
+// {"V":...,"Valid":...}.
+func flatten(v any) any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return v
+	}
+	m := make(map[string]any, rv.NumField())
+	for i := range rv.NumField() {
+		f := rv.Field(i).Interface()
+		if valuer, ok := f.(driver.Valuer); ok {
+			if val, err := valuer.Value(); err == nil {
+				f = val
+			}
+		}
+		field := rv.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		m[name] = f
+	}
+	return m
+}
+
+type AuditStore struct {
+	db *core.DB
+}
+
+func NewAuditStore(db *core.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// AuditFilter selects audit events. Zero fields match every event.
+type AuditFilter struct {
+	Actor      string
+	EntityType string
+	EntityId   int64
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// List returns the events matching f, newest first.
+func (s *AuditStore) List(ctx context.Context, f AuditFilter) (events []models.AuditEvent, err error) {
+	var where []string
+	var args []any
+	if f.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.EntityType != "" {
+		where = append(where, "entity_type = ?")
+		args = append(args, f.EntityType)
+	}
+	if f.EntityId != 0 {
+		where = append(where, "entity_id = ?")
+		args = append(args, f.EntityId)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.Until.UTC())
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+
+	query := "SELECT id, actor, action, entity_type, entity_id, before, after, request_id, created_at FROM audit_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.Id, &e.Actor, &e.Action, &e.EntityType, &e.EntityId, &e.Before, &e.After, &e.RequestId, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Prune deletes events created before t, returning the number deleted.
+func (s *AuditStore) Prune(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM audit_events WHERE created_at < ?", t.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
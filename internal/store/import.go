@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+// ErrDeleted is returned by imports matching a deleted row, which must be
+// restored or purged before it can be imported over.
+var ErrDeleted = errors.New("deleted")
+
+// ImportUser writes u, imported from another instance, in tx: a new username
+// creates the user, and an existing one is deleted if u is. Row ids differ
+// between instances, so u.Id is ignored and set to the id of the user written.
+func ImportUser(ctx context.Context, tx *sql.Tx, u *models.User) error {
+	if u.Username == "" {
+		return errors.New("username must not be empty")
+	}
+	before, err := scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = ?", u.Username))
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := models.InsertUser(ctx, tx, u); err != nil {
+			return mapErr(err, fmt.Sprintf("user %q", u.Username))
+		}
+		return audit(ctx, tx, "create", "user", u.Id, nil, u)
+	}
+	if err != nil {
+		return err
+	}
+
+	u.Id = before.Id
+	switch {
+	case before.DeletedAt.Valid && !u.DeletedAt.Valid:
+		return fmt.Errorf("user %q: %w", u.Username, ErrDeleted)
+	case !before.DeletedAt.Valid && u.DeletedAt.Valid:
+		return deleteUser(ctx, tx, before)
+	}
+	return nil
+}
+
+// ImportOrg writes o, imported from another instance, in tx, as ImportUser
+// does users. A new org is created owned by the user named owner, as every
+// org must have an owner, and then deleted if o is. An existing org keeps its
+// owners.
+func ImportOrg(ctx context.Context, tx *sql.Tx, o *models.Org, owner string) error {
+	if o.Name == "" {
+		return errors.New("org name must not be empty")
+	}
+	before, err := scanOrg(tx.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE name = ?", o.Name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return importNewOrg(ctx, tx, o, owner)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.Id = before.Id
+	switch {
+	case before.DeletedAt.Valid && !o.DeletedAt.Valid:
+		return fmt.Errorf("org %q: %w", o.Name, ErrDeleted)
+	case !before.DeletedAt.Valid && o.DeletedAt.Valid:
+		return deleteOrg(ctx, tx, before)
+	}
+	return nil
+}
+
+func importNewOrg(ctx context.Context, tx *sql.Tx, o *models.Org, owner string) error {
+	if owner == "" {
+		return fmt.Errorf("org %q: owner must not be empty", o.Name)
+	}
+	ownerId, err := activeUserId(ctx, tx, owner)
+	if err != nil {
+		return err
+	}
+
+	deleted := o.DeletedAt
+	o.DeletedAt = sql.Null[time.Time]{}
+	if err := models.InsertOrg(ctx, tx, o); err != nil {
+		return mapErr(err, fmt.Sprintf("org %q", o.Name))
+	}
+	if err := audit(ctx, tx, "create", "org", o.Id, nil, o); err != nil {
+		return err
+	}
+	if err := addMember(ctx, tx, o.Id, ownerId, RoleOwner); err != nil {
+		return err
+	}
+	if deleted.Valid {
+		return deleteOrg(ctx, tx, o)
+	}
+	return nil
+}
+
+// ImportMember writes the membership of the user named username in the org
+// named org, imported from another instance, in tx: the user is added to the
+// org with role, or given role if already a member. Both must exist and not be
+// deleted.
+func ImportMember(ctx context.Context, tx *sql.Tx, org, username, role string) error {
+	r, err := ParseRole(role)
+	if err != nil {
+		return err
+	}
+	orgId, err := activeOrgId(ctx, tx, org)
+	if err != nil {
+		return err
+	}
+	userId, err := activeUserId(ctx, tx, username)
+	if err != nil {
+		return err
+	}
+
+	before, err := models.GetOrgMembership(ctx, tx, orgId, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return addMember(ctx, tx, orgId, userId, r)
+	}
+	if err != nil {
+		return err
+	}
+	if Role(before.Role) == r {
+		return nil
+	}
+	return setRole(ctx, tx, before, r)
+}
+
+// activeUserId and activeOrgId look up the ids of users and orgs by name, as
+// ids differ between instances.
+func activeUserId(ctx context.Context, tx *sql.Tx, username string) (int64, error) {
+	u, err := scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = ? AND deleted_at IS NULL", username))
+	if err != nil {
+		return 0, mapErr(err, fmt.Sprintf("user %q", username))
+	}
+	return u.Id, nil
+}
+
+func activeOrgId(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	o, err := scanOrg(tx.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE name = ? AND deleted_at IS NULL", name))
+	if err != nil {
+		return 0, mapErr(err, fmt.Sprintf("org %q", name))
+	}
+	return o.Id, nil
+}
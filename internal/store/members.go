@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+type Role string
+
+var (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return r, nil
+	default:
+		return "", fmt.Errorf("expected one of: owner, admin, member")
+	}
+}
+
+// ErrLastOwner is returned by changes that would leave an org without an owner.
+var ErrLastOwner = errors.New("org must keep at least one owner")
+
+type Member struct {
+	User models.User
+	Role Role
+}
+
+// AddMember adds the user to the org with role.
+func (s *OrgStore) AddMember(ctx context.Context, orgId, userId int64, role Role) error {
+	return s.db.Update(ctx, func(tx *sql.Tx) error {
+		return addMember(ctx, tx, orgId, userId, role)
+	})
+}
+
+func addMember(ctx context.Context, tx *sql.Tx, orgId, userId int64, role Role) error {
+	if _, err := activeOrg(ctx, tx, orgId); err != nil {
+		return err
+	}
+	if _, err := activeUser(ctx, tx, userId); err != nil {
+		return err
+	}
+	m := &models.OrgMembership{OrgId: orgId, UserId: userId, Role: string(role)}
+	if err := models.InsertOrgMembership(ctx, tx, m); err != nil {
+		return mapErr(err, fmt.Sprintf("org %d member %d", orgId, userId))
+	}
+	return audit(ctx, tx, "add-member", "org", orgId, nil, m)
+}
+
+// SetRole changes the role of an existing member, refusing to demote the org's
+// last owner.
+func (s *OrgStore) SetRole(ctx context.Context, orgId, userId int64, role Role) error {
+	return s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := models.GetOrgMembership(ctx, tx, orgId, userId)
+		if err != nil {
+			return mapErr(err, fmt.Sprintf("org %d member %d", orgId, userId))
+		}
+		return setRole(ctx, tx, before, role)
+	})
+}
+
+func setRole(ctx context.Context, tx *sql.Tx, before *models.OrgMembership, role Role) error {
+	if role != RoleOwner {
+		if err := checkOwners(ctx, tx, before.OrgId, before.UserId); err != nil {
+			return err
+		}
+	}
+	m := &models.OrgMembership{OrgId: before.OrgId, UserId: before.UserId, Role: string(role)}
+	if err := models.UpdateOrgMembership(ctx, tx, m); err != nil {
+		return mapErr(err, fmt.Sprintf("org %d member %d", before.OrgId, before.UserId))
+	}
+	return audit(ctx, tx, "set-role", "org", before.OrgId, before, m)
+}
+
+// RemoveMember removes the user from the org, refusing to remove the org's
+// last owner.
+func (s *OrgStore) RemoveMember(ctx context.Context, orgId, userId int64) error {
+	return s.db.Update(ctx, func(tx *sql.Tx) error {
+		if err := checkOwners(ctx, tx, orgId, userId); err != nil {
+			return err
+		}
+		before, err := models.GetOrgMembership(ctx, tx, orgId, userId)
+		if err != nil {
+			return mapErr(err, fmt.Sprintf("org %d member %d", orgId, userId))
+		}
+		if err := models.DeleteOrgMembership(ctx, tx, orgId, userId); err != nil {
+			return mapErr(err, fmt.Sprintf("org %d member %d", orgId, userId))
+		}
+		return audit(ctx, tx, "remove-member", "org", orgId, before, nil)
+	})
+}
+
+// Members lists the members of the org ordered by username, leaving out
+// deleted users.
+func (s *OrgStore) Members(ctx context.Context, orgId int64) (members []Member, err error) {
+	if _, err := s.Get(ctx, orgId); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT u.id, u.username, m.role FROM org_memberships AS m JOIN users AS u ON u.id = m.user_id WHERE m.org_id = ? AND u.deleted_at IS NULL ORDER BY u.username", orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.User.Id, &m.User.Username, &m.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// checkOwners returns ErrLastOwner if userId is the only owner of orgId who
+// hasn't been deleted.
+func checkOwners(ctx context.Context, tx *sql.Tx, orgId, userId int64) error {
+	var others int
+	var owner bool
+	err := tx.QueryRowContext(ctx, `SELECT count(*) FILTER (WHERE m.user_id != ?), count(*) FILTER (WHERE m.user_id = ?) > 0
+FROM org_memberships AS m JOIN users AS u ON u.id = m.user_id
+WHERE m.org_id = ? AND m.role = 'owner' AND u.deleted_at IS NULL`, userId, userId, orgId).Scan(&others, &owner)
+	if err != nil {
+		return err
+	}
+	if owner && others == 0 {
+		return fmt.Errorf("org %d: %w", orgId, ErrLastOwner)
+	}
+	return nil
+}
+
+// Role returns the user's role in the org. Deleted users and orgs have no
+// roles.
+func (s *OrgStore) Role(ctx context.Context, orgId, userId int64) (Role, error) {
+	var role Role
+	err := s.db.QueryRowContext(ctx, `SELECT m.role FROM org_memberships AS m
+JOIN users AS u ON u.id = m.user_id JOIN orgs AS o ON o.id = m.org_id
+WHERE m.org_id = ? AND m.user_id = ? AND u.deleted_at IS NULL AND o.deleted_at IS NULL`, orgId, userId).Scan(&role)
+	if err != nil {
+		return "", mapErr(err, fmt.Sprintf("org %d member %d", orgId, userId))
+	}
+	return role, nil
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+// OrgStore reads and writes orgs and their memberships. Deleted orgs are kept,
+// hidden from every lookup except GetDeletedByName, until they are restored or
+// purged.
+type OrgStore struct {
+	db *core.DB
+}
+
+func NewOrgStore(db *core.DB) *OrgStore {
+	return &OrgStore{db: db}
+}
+
+const orgColumns = "id, name, deleted_at"
+
+func scanOrg(row interface{ Scan(...any) error }) (*models.Org, error) {
+	var o models.Org
+	if err := row.Scan(&o.Id, &o.Name, &o.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// activeOrg returns the org with id unless it doesn't exist or is deleted.
+func activeOrg(ctx context.Context, db models.DBTX, id int64) (*models.Org, error) {
+	o, err := scanOrg(db.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("org %d", id))
+	}
+	return o, nil
+}
+
+// Create creates an org owned by the user ownerId, as every org must have an
+// owner.
+func (s *OrgStore) Create(ctx context.Context, name string, ownerId int64) (*models.Org, error) {
+	if name == "" {
+		return nil, errors.New("name must not be empty")
+	}
+	o := &models.Org{Name: name}
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		if err := models.InsertOrg(ctx, tx, o); err != nil {
+			return mapErr(err, fmt.Sprintf("org %q", name))
+		}
+		if err := audit(ctx, tx, "create", "org", o.Id, nil, o); err != nil {
+			return err
+		}
+		return addMember(ctx, tx, o.Id, ownerId, RoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *OrgStore) Get(ctx context.Context, id int64) (*models.Org, error) {
+	return activeOrg(ctx, s.db.Reader(), id)
+}
+
+func (s *OrgStore) GetByName(ctx context.Context, name string) (*models.Org, error) {
+	o, err := scanOrg(s.db.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE name = ? AND deleted_at IS NULL", name))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("org %q", name))
+	}
+	return o, nil
+}
+
+// GetDeletedByName returns a deleted org, for restoring it.
+func (s *OrgStore) GetDeletedByName(ctx context.Context, name string) (*models.Org, error) {
+	o, err := scanOrg(s.db.QueryRowContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE name = ? AND deleted_at IS NOT NULL", name))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("deleted org %q", name))
+	}
+	return o, nil
+}
+
+// List returns up to limit orgs with ids greater than after.
+func (s *OrgStore) List(ctx context.Context, after int64, limit int) (_ Page[models.Org], err error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+orgColumns+" FROM orgs WHERE id > ? AND deleted_at IS NULL ORDER BY id LIMIT ?", after, limit+1)
+	if err != nil {
+		return Page[models.Org]{}, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	var orgs []models.Org
+	for rows.Next() {
+		o, err := scanOrg(rows)
+		if err != nil {
+			return Page[models.Org]{}, err
+		}
+		orgs = append(orgs, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.Org]{}, err
+	}
+	return page(orgs, limit, func(o models.Org) int64 { return o.Id }), nil
+}
+
+func (s *OrgStore) Rename(ctx context.Context, id int64, name string) (*models.Org, error) {
+	if name == "" {
+		return nil, errors.New("name must not be empty")
+	}
+	var o *models.Org
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := activeOrg(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		after := *before
+		after.Name = name
+		if err := models.UpdateOrg(ctx, tx, &after); err != nil {
+			return mapErr(err, fmt.Sprintf("org %d", id))
+		}
+		o = &after
+		return audit(ctx, tx, "rename", "org", id, before, o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Delete hides the org and its memberships until it is restored or purged.
+func (s *OrgStore) Delete(ctx context.Context, id int64) error {
+	return s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := activeOrg(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return deleteOrg(ctx, tx, before)
+	})
+}
+
+func deleteOrg(ctx context.Context, tx *sql.Tx, before *models.Org) error {
+	after := *before
+	after.DeletedAt = sql.Null[time.Time]{V: time.Now().UTC(), Valid: true}
+	if err := models.UpdateOrg(ctx, tx, &after); err != nil {
+		return mapErr(err, fmt.Sprintf("org %d", before.Id))
+	}
+	return audit(ctx, tx, "delete", "org", before.Id, before, &after)
+}
+
+// Restore undoes the deletion of an org.
+func (s *OrgStore) Restore(ctx context.Context, id int64) (*models.Org, error) {
+	var o *models.Org
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := models.GetOrg(ctx, tx, id)
+		if err != nil {
+			return mapErr(err, fmt.Sprintf("org %d", id))
+		}
+		if !before.DeletedAt.Valid {
+			return fmt.Errorf("org %d is not deleted", id)
+		}
+		after := *before
+		after.DeletedAt = sql.Null[time.Time]{}
+		if err := models.UpdateOrg(ctx, tx, &after); err != nil {
+			return mapErr(err, fmt.Sprintf("org %d", id))
+		}
+		o = &after
+		return audit(ctx, tx, "restore", "org", id, before, o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
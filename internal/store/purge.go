@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+// DefaultPurgeRetain is how long deleted users and orgs are kept by default,
+// 30 days.
+const DefaultPurgeRetain = 30 * 24 * time.Hour
+
+// Purge permanently removes users deleted before t, along with their org
+// memberships, returning the number removed.
+func (s *UserStore) Purge(ctx context.Context, t time.Time) (n int64, err error) {
+	err = s.db.Update(ctx, func(tx *sql.Tx) error {
+		users, err := deletedBefore(ctx, tx, "users", userColumns, scanUser, t)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := models.DeleteUser(ctx, tx, u.Id); err != nil {
+				return err
+			}
+			if err := audit(ctx, tx, "purge", "user", u.Id, u, nil); err != nil {
+				return err
+			}
+		}
+		n = int64(len(users))
+		return nil
+	})
+	return n, err
+}
+
+// Purge permanently removes orgs deleted before t, along with their
+// memberships, returning the number removed.
+func (s *OrgStore) Purge(ctx context.Context, t time.Time) (n int64, err error) {
+	err = s.db.Update(ctx, func(tx *sql.Tx) error {
+		orgs, err := deletedBefore(ctx, tx, "orgs", orgColumns, scanOrg, t)
+		if err != nil {
+			return err
+		}
+		for _, o := range orgs {
+			if err := models.DeleteOrg(ctx, tx, o.Id); err != nil {
+				return err
+			}
+			if err := audit(ctx, tx, "purge", "org", o.Id, o, nil); err != nil {
+				return err
+			}
+		}
+		n = int64(len(orgs))
+		return nil
+	})
+	return n, err
+}
+
+func deletedBefore[T any](ctx context.Context, tx *sql.Tx, table, columns string, scan func(interface{ Scan(...any) error }) (*T, error), t time.Time) (items []*T, err error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+columns+" FROM "+table+" WHERE deleted_at < ? ORDER BY id", t.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
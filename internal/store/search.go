@@ -0,0 +1,165 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+)
+
+// Kind is a type of entity that can be searched.
+type Kind string
+
+const (
+	KindUser Kind = "user"
+	KindOrg  Kind = "org"
+)
+
+// Kinds lists every searchable kind, in the order results are merged.
+var Kinds = []Kind{KindUser, KindOrg}
+
+func ParseKind(s string) (Kind, error) {
+	for _, k := range Kinds {
+		if string(k) == s {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind %q", s)
+}
+
+// SearchLimit is the most results a search returns.
+const SearchLimit = 50
+
+// Snippets mark each matched term with MarkStart and MarkEnd, which callers
+// replace with whatever highlighting suits their output.
+const (
+	MarkStart = "\x02"
+	MarkEnd   = "\x03"
+)
+
+// Result is one entity matching a search. Higher ranks are better matches.
+type Result struct {
+	Kind    Kind
+	Id      int64
+	Name    string
+	Snippet string
+	Rank    float64
+}
+
+// Highlight returns the snippet with matches wrapped in start and end.
+func (r Result) Highlight(start, end string) string {
+	return strings.NewReplacer(MarkStart, start, MarkEnd, end).Replace(r.Snippet)
+}
+
+// searchIndex describes the full-text table indexing a kind, whose rowids are
+// the ids of the entity table. member, if set, limits the entities to those a
+// user, its one parameter, can see.
+type searchIndex struct {
+	table, entities, name string
+	member                string
+}
+
+var searchIndexes = map[Kind]searchIndex{
+	KindUser: {table: "users_search", entities: "users", name: "username"},
+	KindOrg: {table: "orgs_search", entities: "orgs", name: "name",
+		member: "orgs.id IN (SELECT org_id FROM org_memberships WHERE user_id = ?)"},
+}
+
+// SearchStore finds users and orgs by name. Deleted entities are left in the
+// index, so that restoring them needs no reindexing, and filtered out here.
+type SearchStore struct {
+	db     *core.DB
+	scoped bool
+	userId int64
+}
+
+func NewSearchStore(db *core.DB) *SearchStore {
+	return &SearchStore{db: db}
+}
+
+// As returns a store searching only what the user userId can see: every user,
+// and the orgs they are a member of.
+func (s *SearchStore) As(userId int64) *SearchStore {
+	return &SearchStore{db: s.db, scoped: true, userId: userId}
+}
+
+// Search returns the best matches for query among kinds, or among every kind if
+// none are given. Each word of query matches any word starting with it.
+func (s *SearchStore) Search(ctx context.Context, query string, kinds ...Kind) ([]Result, error) {
+	match := matchQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	if len(kinds) == 0 {
+		kinds = Kinds
+	}
+
+	var results []Result
+	for _, kind := range kinds {
+		idx, ok := searchIndexes[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown kind %q", kind)
+		}
+		r, err := s.search(ctx, kind, idx, match)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r...)
+	}
+
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	if len(results) > SearchLimit {
+		results = results[:SearchLimit]
+	}
+	return results, nil
+}
+
+func (s *SearchStore) search(ctx context.Context, kind Kind, idx searchIndex, match string) (_ []Result, err error) {
+	// bm25 is lower for better matches
+	q := fmt.Sprintf(`SELECT %[2]s.id, %[2]s.%[3]s, highlight(%[1]s, 0, ?, ?), -bm25(%[1]s)
+FROM %[1]s JOIN %[2]s ON %[2]s.id = %[1]s.rowid
+WHERE %[1]s MATCH ? AND %[2]s.deleted_at IS NULL`, idx.table, idx.entities, idx.name)
+	args := []any{MarkStart, MarkEnd, match}
+	if s.scoped && idx.member != "" {
+		q += " AND " + idx.member
+		args = append(args, s.userId)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	var results []Result
+	for rows.Next() {
+		r := Result{Kind: kind}
+		if err := rows.Scan(&r.Id, &r.Name, &r.Snippet, &r.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// matchQuery turns free text into an FTS query matching rows with every word
+// as a prefix. Words are quoted, so that punctuation in the input and words
+// like AND can't change the query's meaning.
+func matchQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = `"` + w + `"*`
+	}
+	return strings.Join(words, " ")
+}
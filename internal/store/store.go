@@ -0,0 +1,50 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+)
+
+// DefaultLimit is the page size used when a list is requested without one.
+const DefaultLimit = 100
+
+// Page is one page of a list ordered by id. Next is the cursor to pass as after
+// for the following page, or zero when this is the last page.
+type Page[T any] struct {
+	Items []T
+	Next  int64
+}
+
+func page[T any](items []T, limit int, id func(T) int64) Page[T] {
+	if len(items) <= limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:limit]
+	return Page[T]{Items: items, Next: id(items[limit-1])}
+}
+
+// mapErr converts driver errors into the store's typed errors, describing the
+// row with what. A foreign key violation means a referenced row is missing.
+func mapErr(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: %w", what, ErrNotFound)
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return fmt.Errorf("%s: %w", what, ErrConflict)
+		case sqlite3.ErrConstraintForeignKey:
+			return fmt.Errorf("%s: %w", what, ErrNotFound)
+		}
+	}
+	return err
+}
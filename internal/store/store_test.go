@@ -0,0 +1,415 @@
+package store_test
+
+import (
+	"errors"
+	"log/slog"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/models"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+func TestUserStore(t *testing.T) {
+	users := store.NewUserStore(openDB(t))
+	ctx := t.Context()
+
+	alice, err := users.Create(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Create(ctx, "alice"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("want ErrConflict creating duplicate, but got %v", err)
+	}
+
+	got, err := users.GetByName(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(alice, got); diff != "" {
+		t.Errorf("get by name mismatch (-want +got):\n%s", diff)
+	}
+
+	bob, err := users.Create(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Rename(ctx, bob.Id, "alice"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("want ErrConflict renaming to taken name, but got %v", err)
+	}
+	if _, err := users.Rename(ctx, bob.Id, "robert"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = users.Get(ctx, bob.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&models.User{Id: bob.Id, Username: "robert"}, got); diff != "" {
+		t.Errorf("get mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := users.Delete(ctx, alice.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, alice.Id); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound deleting twice, but got %v", err)
+	}
+	if _, err := users.Get(ctx, alice.Id); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound after delete, but got %v", err)
+	}
+	if _, err := users.Rename(ctx, alice.Id, "alice"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound renaming deleted user, but got %v", err)
+	}
+}
+
+func TestOrgStoreList(t *testing.T) {
+	db := openDB(t)
+	users, orgs := store.NewUserStore(db), store.NewOrgStore(db)
+	ctx := t.Context()
+
+	owner, err := users.Create(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := orgs.Create(ctx, name, owner.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pages [][]string
+	var after int64
+	for {
+		p, err := orgs.List(ctx, after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, o := range p.Items {
+			names = append(names, o.Name)
+		}
+		pages = append(pages, names)
+		if p.Next == 0 {
+			break
+		}
+		after = p.Next
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if diff := cmp.Diff(want, pages); diff != "" {
+		t.Errorf("pages mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := orgs.GetByName(ctx, "z"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound, but got %v", err)
+	}
+}
+
+func TestMembers(t *testing.T) {
+	db := openDB(t)
+	users, orgs := store.NewUserStore(db), store.NewOrgStore(db)
+	ctx := t.Context()
+
+	var ids []int64
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := users.Create(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.Id)
+	}
+	alice, bob, carol := ids[0], ids[1], ids[2]
+	if _, err := orgs.Create(ctx, "acme", 999); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound creating an org owned by an unknown user, but got %v", err)
+	}
+	if _, err := orgs.GetByName(ctx, "acme"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want no org created without its owner, but got %v", err)
+	}
+	org, err := orgs.Create(ctx, "acme", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := orgs.AddMember(ctx, org.Id, bob, store.RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.AddMember(ctx, org.Id, bob, store.RoleAdmin); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("want ErrConflict adding existing member, but got %v", err)
+	}
+	if err := orgs.AddMember(ctx, org.Id, 999, store.RoleMember); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound adding unknown user, but got %v", err)
+	}
+
+	if err := orgs.SetRole(ctx, org.Id, alice, store.RoleAdmin); !errors.Is(err, store.ErrLastOwner) {
+		t.Errorf("want ErrLastOwner demoting last owner, but got %v", err)
+	}
+	if err := orgs.RemoveMember(ctx, org.Id, alice); !errors.Is(err, store.ErrLastOwner) {
+		t.Errorf("want ErrLastOwner removing last owner, but got %v", err)
+	}
+	if err := users.Delete(ctx, alice); !errors.Is(err, store.ErrLastOwner) {
+		t.Errorf("want ErrLastOwner deleting last owner, but got %v", err)
+	}
+
+	if err := orgs.SetRole(ctx, org.Id, bob, store.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.SetRole(ctx, org.Id, carol, store.RoleOwner); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound setting role of non-member, but got %v", err)
+	}
+	if err := orgs.AddMember(ctx, org.Id, carol, store.RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.RemoveMember(ctx, org.Id, carol); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := orgs.Members(ctx, org.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []store.Member{{User: models.User{Id: bob, Username: "bob"}, Role: store.RoleOwner}}
+	if diff := cmp.Diff(want, members); diff != "" {
+		t.Errorf("members mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	db := openDB(t)
+	users, orgs := store.NewUserStore(db), store.NewOrgStore(db)
+	ctx := t.Context()
+
+	alice, err := users.Create(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := users.Create(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	org, err := orgs.Create(ctx, "acme", alice.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.AddMember(ctx, org.Id, bob.Id, store.RoleOwner); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.Delete(ctx, alice.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.GetByName(ctx, "alice"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want ErrNotFound for deleted user, but got %v", err)
+	}
+	if _, err := users.Create(ctx, "alice"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("want ErrConflict reusing deleted name, but got %v", err)
+	}
+	if _, err := orgs.Role(ctx, org.Id, alice.Id); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want no role for deleted user, but got %v", err)
+	}
+	// a deleted owner doesn't count towards the org's owners
+	if err := orgs.RemoveMember(ctx, org.Id, bob.Id); !errors.Is(err, store.ErrLastOwner) {
+		t.Errorf("want ErrLastOwner, but got %v", err)
+	}
+
+	deleted, err := users.GetDeletedByName(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Restore(ctx, deleted.Id); err != nil {
+		t.Fatal(err)
+	}
+	members, err := orgs.Members(ctx, org.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("want 2 members after restore, but got %v", members)
+	}
+
+	if err := orgs.Delete(ctx, org.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, bob.Id); err != nil {
+		t.Fatal(err)
+	}
+	n, err := users.Purge(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("want 1 user purged, but got %d", n)
+	}
+	if _, err := users.Create(ctx, "bob"); err != nil {
+		t.Errorf("want purged name to be reusable, but got %v", err)
+	}
+	if n, err := orgs.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("want no orgs purged within retention, but got %d, %v", n, err)
+	}
+	if _, err := orgs.GetDeletedByName(ctx, "acme"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	db := openDB(t)
+	users, audit := store.NewUserStore(db), store.NewAuditStore(db)
+	ctx := core.WithRequestId(core.WithActor(t.Context(), "cli:root"), "req-1")
+
+	start := time.Now()
+	u, err := users.Create(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Rename(ctx, u.Id, "alicia"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Create(t.Context(), "alicia"); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("want ErrConflict, but got %v", err)
+	}
+
+	events, err := audit.List(t.Context(), store.AuditFilter{EntityType: "user", EntityId: u.Id, Since: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type event struct {
+		Actor, Action, Before, After, RequestId string
+	}
+	var got []event
+	for _, e := range events {
+		got = append(got, event{e.Actor, e.Action, e.Before.V, e.After.V, e.RequestId.V})
+	}
+	want := []event{
+		{"cli:root", "rename", `{"deleted_at":null,"id":1,"username":"alice"}`, `{"deleted_at":null,"id":1,"username":"alicia"}`, "req-1"},
+		{"cli:root", "create", "", `{"deleted_at":null,"id":1,"username":"alice"}`, "req-1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	// the failed create rolled back without an event
+	if events, err := audit.List(t.Context(), store.AuditFilter{Actor: "unknown"}); err != nil || len(events) != 0 {
+		t.Errorf("want no events by unknown actor, but got %v, %v", events, err)
+	}
+
+	n, err := audit.Prune(t.Context(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 events pruned, but got %d", n)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := openDB(t)
+	users, orgs, search := store.NewUserStore(db), store.NewOrgStore(db), store.NewSearchStore(db)
+	ctx := t.Context()
+
+	alice, err := users.Create(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := users.Create(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	band, err := orgs.Create(ctx, "alice band", alice.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orgs.Create(ctx, "bob's band, the long-running alice tribute", bob.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := search.Search(ctx, "ali", store.KindOrg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snippets []string
+	for _, r := range got {
+		snippets = append(snippets, r.Highlight("[", "]"))
+	}
+	want := []string{"[alice] band", "bob's band, the long-running [alice] tribute"}
+	if diff := cmp.Diff(want, snippets); diff != "" {
+		t.Errorf("snippets mismatch, shorter names should rank first (-want +got):\n%s", diff)
+	}
+
+	got, err = search.Search(ctx, `"alice: band`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range got {
+		names = append(names, string(r.Kind)+":"+r.Name)
+	}
+	slices.Sort(names)
+	want = []string{"org:alice band", "org:bob's band, the long-running alice tribute"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = search.As(bob.Id).Search(ctx, "band")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "bob's band, the long-running alice tribute" {
+		t.Errorf("want only the org bob is a member of, but got %+v", got)
+	}
+
+	if _, err := users.Rename(ctx, alice.Id, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.Delete(ctx, band.Id); err != nil {
+		t.Fatal(err)
+	}
+	got, err = search.Search(ctx, "alice band")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "bob's band, the long-running alice tribute" {
+		t.Errorf("want only the undeleted org after rename and delete, but got %+v", got)
+	}
+	got, err = search.Search(ctx, "carol", store.KindUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Id != alice.Id {
+		t.Errorf("want renamed user %d, but got %+v", alice.Id, got)
+	}
+
+	if got, err := search.Search(ctx, " -*- "); err != nil || got != nil {
+		t.Errorf("want no results for a query without words, but got %+v, %v", got, err)
+	}
+}
+
+func openDB(t testing.TB) *core.DB {
+	t.Helper()
+
+	cfg := core.Config{
+		DbConnString:  path.Join(t.TempDir(), "data.db"),
+		DbJournalMode: "wal",
+		DbBusyTimeout: time.Second,
+		DbForeignKeys: true,
+	}
+	db, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if _, err := db.ExecContext(t.Context(), schema.Schema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
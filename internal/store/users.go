@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/models"
+)
+
+// UserStore reads and writes users. Deleted users are kept, hidden from every
+// lookup except GetDeletedByName, until they are restored or purged.
+type UserStore struct {
+	db *core.DB
+}
+
+func NewUserStore(db *core.DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+const userColumns = "id, username, deleted_at"
+
+func scanUser(row interface{ Scan(...any) error }) (*models.User, error) {
+	var u models.User
+	if err := row.Scan(&u.Id, &u.Username, &u.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// activeUser returns the user with id unless it doesn't exist or is deleted.
+func activeUser(ctx context.Context, db models.DBTX, id int64) (*models.User, error) {
+	u, err := scanUser(db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ? AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("user %d", id))
+	}
+	return u, nil
+}
+
+func (s *UserStore) Create(ctx context.Context, username string) (*models.User, error) {
+	if username == "" {
+		return nil, errors.New("username must not be empty")
+	}
+	u := &models.User{Username: username}
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		if err := models.InsertUser(ctx, tx, u); err != nil {
+			return mapErr(err, fmt.Sprintf("user %q", username))
+		}
+		return audit(ctx, tx, "create", "user", u.Id, nil, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *UserStore) Get(ctx context.Context, id int64) (*models.User, error) {
+	return activeUser(ctx, s.db.Reader(), id)
+}
+
+func (s *UserStore) GetByName(ctx context.Context, username string) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = ? AND deleted_at IS NULL", username))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("user %q", username))
+	}
+	return u, nil
+}
+
+// GetDeletedByName returns a deleted user, for restoring it.
+func (s *UserStore) GetDeletedByName(ctx context.Context, username string) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = ? AND deleted_at IS NOT NULL", username))
+	if err != nil {
+		return nil, mapErr(err, fmt.Sprintf("deleted user %q", username))
+	}
+	return u, nil
+}
+
+// List returns up to limit users with ids greater than after.
+func (s *UserStore) List(ctx context.Context, after int64, limit int) (_ Page[models.User], err error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id > ? AND deleted_at IS NULL ORDER BY id LIMIT ?", after, limit+1)
+	if err != nil {
+		return Page[models.User]{}, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	var users []models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return Page[models.User]{}, err
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return Page[models.User]{}, err
+	}
+	return page(users, limit, func(u models.User) int64 { return u.Id }), nil
+}
+
+func (s *UserStore) Rename(ctx context.Context, id int64, username string) (*models.User, error) {
+	if username == "" {
+		return nil, errors.New("username must not be empty")
+	}
+	var u *models.User
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := activeUser(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		after := *before
+		after.Username = username
+		if err := models.UpdateUser(ctx, tx, &after); err != nil {
+			return mapErr(err, fmt.Sprintf("user %d", id))
+		}
+		u = &after
+		return audit(ctx, tx, "rename", "user", id, before, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Delete hides the user and their org memberships until the user is restored
+// or purged, refusing to delete the last owner of an org.
+func (s *UserStore) Delete(ctx context.Context, id int64) error {
+	return s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := activeUser(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return deleteUser(ctx, tx, before)
+	})
+}
+
+func deleteUser(ctx context.Context, tx *sql.Tx, before *models.User) error {
+	var org string
+	err := tx.QueryRowContext(ctx, `SELECT o.name FROM org_memberships AS m JOIN orgs AS o ON o.id = m.org_id
+WHERE m.user_id = ? AND m.role = 'owner' AND NOT EXISTS (
+	SELECT 1 FROM org_memberships AS om JOIN users AS u ON u.id = om.user_id
+	WHERE om.org_id = m.org_id AND om.role = 'owner' AND om.user_id != m.user_id AND u.deleted_at IS NULL
+) LIMIT 1`, before.Id).Scan(&org)
+	if err == nil {
+		return fmt.Errorf("user %d is the only owner of org %q: %w", before.Id, org, ErrLastOwner)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	after := *before
+	after.DeletedAt = sql.Null[time.Time]{V: time.Now().UTC(), Valid: true}
+	if err := models.UpdateUser(ctx, tx, &after); err != nil {
+		return mapErr(err, fmt.Sprintf("user %d", before.Id))
+	}
+	return audit(ctx, tx, "delete", "user", before.Id, before, &after)
+}
+
+// Restore undoes the deletion of a user.
+func (s *UserStore) Restore(ctx context.Context, id int64) (*models.User, error) {
+	var u *models.User
+	err := s.db.Update(ctx, func(tx *sql.Tx) error {
+		before, err := models.GetUser(ctx, tx, id)
+		if err != nil {
+			return mapErr(err, fmt.Sprintf("user %d", id))
+		}
+		if !before.DeletedAt.Valid {
+			return fmt.Errorf("user %d is not deleted", id)
+		}
+		after := *before
+		after.DeletedAt = sql.Null[time.Time]{}
+		if err := models.UpdateUser(ctx, tx, &after); err != nil {
+			return mapErr(err, fmt.Sprintf("user %d", id))
+		}
+		u = &after
+		return audit(ctx, tx, "restore", "user", id, before, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,549 @@
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/models"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+)
+
+type Format string
+
+var (
+	NDJSON Format = "ndjson"
+	CSV    Format = "csv"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case NDJSON, CSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("expected one of: ndjson, csv")
+	}
+}
+
+// Table looks up a table that can be transferred, which is any table other than
+// the schema version tables.
+func Table(ctx context.Context, db *core.DB, name string) (schema.Table, error) {
+	tables, err := schema.Tables(ctx, db.Reader())
+	if err != nil {
+		return schema.Table{}, err
+	}
+	for _, t := range tables {
+		if t.Name == name && !strings.HasPrefix(t.Name, "schema_") {
+			return t, nil
+		}
+	}
+	return schema.Table{}, fmt.Errorf("unknown table: %s", name)
+}
+
+// naturalKey is the column used to select and match rows across instances: the
+// first single-column unique key, falling back to the primary key.
+func naturalKey(t schema.Table) []string {
+	for _, key := range t.Unique {
+		if len(key) == 1 {
+			return key
+		}
+	}
+	if len(t.Unique) > 0 {
+		return t.Unique[0]
+	}
+	var pk []string
+	for _, c := range t.PrimaryKey() {
+		pk = append(pk, c.Name)
+	}
+	return pk
+}
+
+// Export streams rows of t to w. With keys, only rows whose natural key matches
+// one of keys are written.
+func Export(ctx context.Context, db *core.DB, w io.Writer, t schema.Table, format Format, keys []string) (n int, err error) {
+	sel := selectRows(t)
+	cols := sel.columns
+	query := "SELECT " + sel.query
+	where := slices.Clone(sel.where)
+	var args []any
+	if len(keys) > 0 {
+		if sel.key == "" {
+			return 0, fmt.Errorf("table %s has no single-column key to select by", t.Name)
+		}
+		where = append(where, fmt.Sprintf("%s IN (%s)", sel.key, strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")))
+		for _, k := range keys {
+			args = append(args, k)
+		}
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if sel.order != "" {
+		query += " ORDER BY " + sel.order
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+
+	enc := newEncoder(w, format, cols)
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		if err := enc.encode(values); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, enc.flush()
+}
+
+// selection is how rows are selected for export: the columns written, the
+// query selecting them after SELECT, its conditions, the expression export
+// keys match and the order rows are written in.
+type selection struct {
+	columns []string
+	query   string
+	where   []string
+	key     string
+	order   string
+}
+
+func selectRows(t schema.Table) selection {
+	if st, ok := storeTables[t.Name]; ok && st.columns != nil {
+		return st.selection
+	}
+
+	sel := selection{columns: make([]string, len(t.Columns))}
+	for i, c := range t.Columns {
+		sel.columns[i] = c.Name
+	}
+	sel.query = fmt.Sprintf("%s FROM %s", strings.Join(sel.columns, ", "), t.Name)
+	if nk := naturalKey(t); len(nk) == 1 {
+		sel.key = nk[0]
+	}
+	var pk []string
+	for _, c := range t.PrimaryKey() {
+		pk = append(pk, c.Name)
+	}
+	sel.order = strings.Join(pk, ", ")
+	return sel
+}
+
+type encoder struct {
+	format Format
+	cols   []string
+	json   *json.Encoder
+	csv    *csv.Writer
+	header bool
+}
+
+func newEncoder(w io.Writer, format Format, cols []string) *encoder {
+	e := &encoder{format: format, cols: cols}
+	if format == CSV {
+		e.csv = csv.NewWriter(w)
+	} else {
+		e.json = json.NewEncoder(w)
+	}
+	return e
+}
+
+func (e *encoder) encode(values []any) error {
+	if e.format == CSV {
+		if !e.header {
+			if err := e.csv.Write(e.cols); err != nil {
+				return err
+			}
+			e.header = true
+		}
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = csvValue(v)
+		}
+		return e.csv.Write(record)
+	}
+
+	obj := make(map[string]any, len(values))
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		obj[e.cols[i]] = v
+	}
+	return e.json.Encode(obj)
+}
+
+func (e *encoder) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type ImportOptions struct {
+	BatchSize int
+	DryRun    bool
+}
+
+type ImportStats struct {
+	Rows    int
+	Batches int
+}
+
+var errDryRun = errors.New("dry run")
+
+// storeTable transfers a table owned by the stores. Its records are imported
+// through them, so imported users, orgs and members are checked and audited
+// like any other change. Tables referring to users and orgs are exported with
+// their names in place of their ids, which differ between instances.
+type storeTable struct {
+	// selection, if set, replaces the table's columns.
+	selection
+
+	importer func(context.Context, *sql.Tx, map[string]any) error
+}
+
+var storeTables = map[string]storeTable{
+	"users": {importer: importUser},
+	"orgs": {
+		// owner is the username of an owner, preferring ones not deleted,
+		// to create the org with.
+		selection: selection{
+			columns: []string{"id", "name", "deleted_at", "owner"},
+			query:   "o.id, o.name, o.deleted_at, (SELECT u.username FROM org_memberships AS m JOIN users AS u ON u.id = m.user_id WHERE m.org_id = o.id AND m.role = 'owner' ORDER BY u.deleted_at IS NOT NULL, u.id LIMIT 1) FROM orgs AS o",
+			key:     "o.name",
+			order:   "o.id",
+		},
+		importer: importOrg,
+	},
+	"org_memberships": {
+		// Members of deleted users and orgs are hidden, and left out.
+		selection: selection{
+			columns: []string{"org", "username", "role"},
+			query:   "o.name, u.username, m.role FROM org_memberships AS m JOIN orgs AS o ON o.id = m.org_id JOIN users AS u ON u.id = m.user_id",
+			where:   []string{"o.deleted_at IS NULL", "u.deleted_at IS NULL"},
+			key:     "o.name",
+			order:   "m.org_id, m.user_id",
+		},
+		importer: importMember,
+	},
+}
+
+// Import upserts the records read from r into t, matching existing rows on the
+// table's natural key. Batches are committed in separate transactions, except
+// in a dry run, where every batch runs in one transaction that is rolled back.
+// Changes are audited with the actor "import".
+func Import(ctx context.Context, db *core.DB, r io.Reader, t schema.Table, format Format, opts ImportOptions) (stats ImportStats, err error) {
+	ctx = core.WithActor(ctx, "import")
+	dec, err := newDecoder(r, format, t)
+	if err != nil {
+		return stats, err
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 1
+	}
+
+	key := naturalKey(t)
+	if len(key) == 0 {
+		return stats, fmt.Errorf("table %s has no key to import by", t.Name)
+	}
+
+	batches := func(tx *sql.Tx) error {
+		for {
+			var batch []map[string]any
+			for len(batch) < opts.BatchSize {
+				rec, err := dec.decode()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return fmt.Errorf("record %d: %v", stats.Rows+len(batch)+1, err)
+				}
+				batch = append(batch, rec)
+			}
+			if len(batch) == 0 {
+				return nil
+			}
+
+			write := func(tx *sql.Tx) error {
+				for i, rec := range batch {
+					var err error
+					if st, ok := storeTables[t.Name]; ok {
+						err = st.importer(ctx, tx, rec)
+					} else {
+						err = upsert(ctx, tx, t, key, rec)
+					}
+					if err != nil {
+						return fmt.Errorf("record %d: %w", stats.Rows+i+1, err)
+					}
+				}
+				return nil
+			}
+			var err error
+			if tx != nil {
+				err = write(tx)
+			} else {
+				err = db.Update(ctx, write)
+			}
+			if err != nil {
+				return err
+			}
+			stats.Rows += len(batch)
+			stats.Batches++
+		}
+	}
+
+	if !opts.DryRun {
+		return stats, batches(nil)
+	}
+	err = db.Update(ctx, func(tx *sql.Tx) error {
+		if err := batches(tx); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return stats, err
+}
+
+func upsert(ctx context.Context, tx *sql.Tx, t schema.Table, key []string, rec map[string]any) error {
+	var cols, updates []string
+	var args []any
+	for _, c := range t.Columns {
+		v, ok := rec[c.Name]
+		if !ok {
+			continue
+		}
+		// row ids differ between instances, so rows matched on another key
+		// keep the id already assigned here
+		if t.RowId(c) && !slices.Contains(key, c.Name) {
+			continue
+		}
+		cols = append(cols, c.Name)
+		args = append(args, v)
+		if !slices.Contains(key, c.Name) {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", c.Name, c.Name))
+		}
+	}
+	for _, k := range key {
+		if _, ok := rec[k]; !ok {
+			return fmt.Errorf("missing key column %s", k)
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		t.Name,
+		strings.Join(cols, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "),
+		strings.Join(key, ", "),
+		action,
+	)
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func importUser(ctx context.Context, tx *sql.Tx, rec map[string]any) error {
+	var u models.User
+	err := errors.Join(
+		recString(rec, "username", &u.Username),
+		recTime(rec, "deleted_at", &u.DeletedAt),
+	)
+	if err != nil {
+		return err
+	}
+	return store.ImportUser(ctx, tx, &u)
+}
+
+func importOrg(ctx context.Context, tx *sql.Tx, rec map[string]any) error {
+	var o models.Org
+	var owner string
+	err := errors.Join(
+		recString(rec, "name", &o.Name),
+		recTime(rec, "deleted_at", &o.DeletedAt),
+	)
+	if err != nil {
+		return err
+	}
+	if rec["owner"] != nil {
+		if err := recString(rec, "owner", &owner); err != nil {
+			return err
+		}
+	}
+	return store.ImportOrg(ctx, tx, &o, owner)
+}
+
+func importMember(ctx context.Context, tx *sql.Tx, rec map[string]any) error {
+	var org, username, role string
+	err := errors.Join(
+		recString(rec, "org", &org),
+		recString(rec, "username", &username),
+		recString(rec, "role", &role),
+	)
+	if err != nil {
+		return err
+	}
+	return store.ImportMember(ctx, tx, org, username, role)
+}
+
+// recString and recTime read a column of a decoded record, which holds
+// strings from CSV and strings or numbers from JSON.
+func recString(rec map[string]any, col string, dst *string) error {
+	switch v := rec[col].(type) {
+	case string:
+		*dst = v
+		return nil
+	case nil:
+		return fmt.Errorf("missing key column %s", col)
+	default:
+		return fmt.Errorf("column %s: want a string, but got %v", col, v)
+	}
+}
+
+func recTime(rec map[string]any, col string, dst *sql.Null[time.Time]) error {
+	switch v := rec[col].(type) {
+	case nil:
+		*dst = sql.Null[time.Time]{}
+		return nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", col, err)
+		}
+		*dst = sql.Null[time.Time]{V: t.UTC(), Valid: true}
+		return nil
+	default:
+		return fmt.Errorf("column %s: want a time, but got %v", col, v)
+	}
+}
+
+type decoder struct {
+	table   schema.Table
+	columns []string // the columns of a store table, if it replaces them
+	json    *json.Decoder
+	csv     *csv.Reader
+	cols    []string
+}
+
+func newDecoder(r io.Reader, format Format, t schema.Table) (*decoder, error) {
+	d := &decoder{table: t, columns: storeTables[t.Name].columns}
+	if format != CSV {
+		d.json = json.NewDecoder(r)
+		d.json.UseNumber()
+		return d, nil
+	}
+
+	d.csv = csv.NewReader(r)
+	header, err := d.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range header {
+		if !d.known(col) {
+			return nil, fmt.Errorf("unknown column %s", col)
+		}
+	}
+	d.cols = header
+	return d, nil
+}
+
+func (d *decoder) decode() (map[string]any, error) {
+	if d.csv != nil {
+		if d.cols == nil {
+			return nil, io.EOF
+		}
+		record, err := d.csv.Read()
+		if err != nil {
+			return nil, err
+		}
+		rec := make(map[string]any, len(record))
+		for i, v := range record {
+			col := d.cols[i]
+			if v == "" && d.nullable(col) {
+				rec[col] = nil
+			} else {
+				rec[col] = v
+			}
+		}
+		return rec, nil
+	}
+
+	var obj map[string]any
+	if err := d.json.Decode(&obj); err != nil {
+		return nil, err
+	}
+	for col, v := range obj {
+		if !d.known(col) {
+			return nil, fmt.Errorf("unknown column %s", col)
+		}
+		if n, ok := v.(json.Number); ok {
+			if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+				obj[col] = i
+			} else if f, err := n.Float64(); err == nil {
+				obj[col] = f
+			}
+		}
+	}
+	return obj, nil
+}
+
+func (d *decoder) known(col string) bool {
+	if d.columns != nil {
+		return slices.Contains(d.columns, col)
+	}
+	_, ok := d.table.Column(col)
+	return ok
+}
+
+// nullable reports whether an empty CSV value of col is null. Every column
+// replaced by a store table is, as none of them can be empty.
+func (d *decoder) nullable(col string) bool {
+	if d.columns != nil {
+		return true
+	}
+	c, _ := d.table.Column(col)
+	return !c.NotNull
+}
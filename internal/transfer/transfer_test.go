@@ -0,0 +1,276 @@
+package transfer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/core"
+	"github.com/jonathonwebb/tilde/internal/schema"
+	"github.com/jonathonwebb/tilde/internal/store"
+	"github.com/jonathonwebb/tilde/internal/transfer"
+)
+
+func TestExportImport(t *testing.T) {
+	src := openDB(t)
+	if _, err := src.ExecContext(t.Context(), "INSERT INTO users (username) VALUES ('alice'), ('bob'), ('carol')"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []transfer.Format{transfer.NDJSON, transfer.CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			users, err := transfer.Table(t.Context(), src, "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			n, err := transfer.Export(t.Context(), src, &b, users, format, []string{"bob", "carol"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 2 {
+				t.Errorf("want 2 rows exported, but got %d", n)
+			}
+
+			dest := openDB(t)
+			if _, err := dest.ExecContext(t.Context(), "INSERT INTO users (username) VALUES ('carol')"); err != nil {
+				t.Fatal(err)
+			}
+
+			exported := b.String()
+			stats, err := transfer.Import(t.Context(), dest, strings.NewReader(exported), users, format, transfer.ImportOptions{BatchSize: 1, DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Rows != 2 {
+				t.Errorf("want 2 rows in dry run, but got %d", stats.Rows)
+			}
+			if diff := cmp.Diff([]string{"1:carol"}, usernames(t, dest)); diff != "" {
+				t.Errorf("dry run changed rows (-want +got):\n%s", diff)
+			}
+
+			stats, err = transfer.Import(t.Context(), dest, strings.NewReader(exported), users, format, transfer.ImportOptions{BatchSize: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Rows != 2 || stats.Batches != 2 {
+				t.Errorf("want 2 rows in 2 batches, but got %+v", stats)
+			}
+			if diff := cmp.Diff([]string{"1:carol", "2:bob"}, usernames(t, dest)); diff != "" {
+				t.Errorf("imported rows mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("unknown column", func(t *testing.T) {
+		users, err := transfer.Table(t.Context(), src, "users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = transfer.Import(t.Context(), src, strings.NewReader(`{"username":"dave","email":"d@example.com"}`), users, transfer.NDJSON, transfer.ImportOptions{})
+		if err == nil {
+			t.Error("want unknown column error, but got nil")
+		}
+	})
+
+	t.Run("schema table", func(t *testing.T) {
+		if _, err := transfer.Table(t.Context(), src, "schema_migrations"); err == nil {
+			t.Error("want unknown table error, but got nil")
+		}
+	})
+}
+
+func TestImportThroughStores(t *testing.T) {
+	db := openDB(t)
+	ctx := core.WithActor(t.Context(), "cli:root")
+	users := store.NewUserStore(db)
+	if _, err := users.Create(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	bob, err := users.Create(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Delete(ctx, bob.Id); err != nil {
+		t.Fatal(err)
+	}
+	table, err := transfer.Table(t.Context(), db, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `{"id":7,"username":"alice","deleted_at":"2026-01-02T03:04:05Z"}
+{"id":8,"username":"carol","deleted_at":null}
+`
+	if _, err := transfer.Import(t.Context(), db, strings.NewReader(in), table, transfer.NDJSON, transfer.ImportOptions{BatchSize: 10}); err != nil {
+		t.Fatal(err)
+	}
+	events, err := store.NewAuditStore(db).List(t.Context(), store.AuditFilter{Actor: "import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, fmt.Sprintf("%s %s %d", e.Action, e.EntityType, e.EntityId))
+	}
+	if diff := cmp.Diff([]string{"create user 3", "delete user 1"}, got); diff != "" {
+		t.Errorf("audit events mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = transfer.Import(t.Context(), db, strings.NewReader(`{"username":"bob"}`), table, transfer.NDJSON, transfer.ImportOptions{})
+	if !errors.Is(err, store.ErrDeleted) {
+		t.Errorf("want ErrDeleted importing a deleted user, but got %v", err)
+	}
+}
+
+func TestImportByName(t *testing.T) {
+	ctx := core.WithActor(t.Context(), "cli:root")
+	src := openDB(t)
+	users := store.NewUserStore(src)
+	var ids []int64
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u, err := users.Create(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.Id)
+	}
+	orgs := store.NewOrgStore(src)
+	acme, err := orgs.Create(ctx, "acme", ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.AddMember(ctx, acme.Id, ids[1], store.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.AddMember(ctx, acme.Id, ids[2], store.RoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orgs.Create(ctx, "globex", ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows created first shift the ids of the imported ones, so matching on
+	// the exported ids would attach members to the wrong users and orgs.
+	dest := openDB(t)
+	zed, err := store.NewUserStore(dest).Create(ctx, "zed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.NewOrgStore(dest).Create(ctx, "initech", zed.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each format imports into the same database, so the second import
+	// matches every row of the first and changes nothing.
+	for _, format := range []transfer.Format{transfer.NDJSON, transfer.CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			for _, name := range []string{"users", "orgs", "org_memberships"} {
+				table, err := transfer.Table(t.Context(), src, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var b bytes.Buffer
+				if _, err := transfer.Export(t.Context(), src, &b, table, format, nil); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := transfer.Import(t.Context(), dest, &b, table, format, transfer.ImportOptions{BatchSize: 10}); err != nil {
+					t.Fatalf("import %s: %v", name, err)
+				}
+			}
+
+			want := []string{
+				"acme alice owner",
+				"acme bob admin",
+				"acme carol member",
+				"globex bob owner",
+				"initech zed owner",
+			}
+			if diff := cmp.Diff(want, memberships(t, dest)); diff != "" {
+				t.Errorf("imported memberships mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("org without owner", func(t *testing.T) {
+		table, err := transfer.Table(t.Context(), dest, "orgs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = transfer.Import(t.Context(), dest, strings.NewReader(`{"name":"hooli"}`), table, transfer.NDJSON, transfer.ImportOptions{})
+		if err == nil {
+			t.Error("want error importing an org without an owner, but got nil")
+		}
+	})
+}
+
+func memberships(t testing.TB, db *core.DB) []string {
+	t.Helper()
+
+	rows, err := db.QueryContext(t.Context(), "SELECT o.name, u.username, m.role FROM org_memberships AS m JOIN orgs AS o ON o.id = m.org_id JOIN users AS u ON u.id = m.user_id ORDER BY o.name, u.username")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var got []string
+	for rows.Next() {
+		var org, user, role string
+		if err := rows.Scan(&org, &user, &role); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, org+" "+user+" "+role)
+	}
+	return got
+}
+
+func usernames(t testing.TB, db *core.DB) []string {
+	t.Helper()
+
+	rows, err := db.QueryContext(t.Context(), "SELECT id, username FROM users ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var got []string
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", id, name))
+	}
+	return got
+}
+
+func openDB(t testing.TB) *core.DB {
+	t.Helper()
+
+	cfg := core.Config{
+		DbConnString:  path.Join(t.TempDir(), "data.db"),
+		DbJournalMode: "wal",
+		DbBusyTimeout: time.Second,
+	}
+	db, err := cfg.OpenDB(t.Context(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if _, err := db.ExecContext(t.Context(), schema.Schema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,32 @@
+package view
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// island renders the element an entrypoint mounts into, with props as the
+// URL-encoded JSON its data-props attribute carries, followed by the
+// entrypoint's script. An entrypoint mounts into every element whose
+// data-island is its base name, e.g. main for build/main.tsx, and since a
+// module script runs once however often it is included, a page may hold any
+// number of islands.
+func (r resolver) island(entrypoint string, props any) (template.HTML, error) {
+	a, err := r(entrypoint)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return "", fmt.Errorf("island %q props: %w", entrypoint, err)
+	}
+	name := strings.TrimSuffix(path.Base(entrypoint), path.Ext(entrypoint))
+	return template.HTML(fmt.Sprintf(`<div data-island="%s" data-props="%s"></div>%s`,
+		template.HTMLEscapeString(name),
+		template.HTMLEscapeString(url.PathEscape(string(b))),
+		scriptTag(a))), nil
+}
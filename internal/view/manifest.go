@@ -0,0 +1,107 @@
+package view
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ManifestPath is where tilde assets writes the manifest in the public dir. It
+// is hidden, as static.Handler doesn't serve hidden files.
+const ManifestPath = "build/.meta.json"
+
+// Asset is a built file, at its public URL path with its subresource integrity.
+type Asset struct {
+	Path string `json:"path"`
+	SRI  string `json:"sri"`
+}
+
+// Manifest maps logical entrypoints, such as build/main.tsx, to their outputs.
+type Manifest map[string]Asset
+
+func ReadManifest(fsys fs.FS) (Manifest, error) {
+	b, err := fs.ReadFile(fsys, ManifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("asset manifest %s not found, run tilde assets: %w", ManifestPath, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read asset manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("read asset manifest: %w", err)
+	}
+	return m, nil
+}
+
+func (m Manifest) Asset(entrypoint string) (Asset, error) {
+	a, ok := m[entrypoint]
+	if !ok {
+		return Asset{}, fmt.Errorf("asset %q is not in the manifest, run tilde assets", entrypoint)
+	}
+	return a, nil
+}
+
+// devAsset resolves an entrypoint to the unhashed output the dev server's
+// esbuild watcher writes for it, which has no fixed integrity.
+func devAsset(entrypoint string) (Asset, error) {
+	ext := path.Ext(entrypoint)
+	switch ext {
+	case ".js", ".jsx", ".ts", ".tsx":
+		ext = ".js"
+	case ".css":
+	default:
+		return Asset{}, fmt.Errorf("asset %q is not a script or stylesheet", entrypoint)
+	}
+	return Asset{Path: "/public/" + strings.TrimSuffix(entrypoint, path.Ext(entrypoint)) + ext}, nil
+}
+
+// resolver finds the asset built for an entrypoint.
+type resolver func(entrypoint string) (Asset, error)
+
+// funcs returns the template helpers resolving entrypoints through r.
+func (r resolver) funcs() template.FuncMap {
+	return template.FuncMap{
+		"asset": func(entrypoint string) (string, error) {
+			a, err := r(entrypoint)
+			return a.Path, err
+		},
+		"integrity": func(entrypoint string) (string, error) {
+			a, err := r(entrypoint)
+			return a.SRI, err
+		},
+		"script": func(entrypoint string) (template.HTML, error) {
+			a, err := r(entrypoint)
+			if err != nil {
+				return "", err
+			}
+			return template.HTML(scriptTag(a)), nil
+		},
+		"island": r.island,
+		"stylesheet": func(entrypoint string) (template.HTML, error) {
+			a, err := r(entrypoint)
+			if err != nil {
+				return "", err
+			}
+			return template.HTML(fmt.Sprintf(`<link rel="stylesheet" href="%s"%s>`,
+				template.HTMLEscapeString(a.Path), integrityAttrs(a))), nil
+		},
+	}
+}
+
+func scriptTag(a Asset) string {
+	return fmt.Sprintf(`<script type="module" src="%s"%s></script>`,
+		template.HTMLEscapeString(a.Path), integrityAttrs(a))
+}
+
+// integrityAttrs returns the attributes checking a's integrity, if it has one.
+func integrityAttrs(a Asset) string {
+	if a.SRI == "" {
+		return ""
+	}
+	return fmt.Sprintf(` integrity="%s" crossorigin="anonymous"`, template.HTMLEscapeString(a.SRI))
+}
@@ -0,0 +1,190 @@
+package view
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+	identifier    = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+)
+
+// TypeScript writes an interface for each struct in types, and for the named
+// structs they refer to, describing the JSON encoding/json gives them. Fields
+// tagged omitempty or omitzero are optional, and pointers, slices and maps may
+// be null.
+func TypeScript(w io.Writer, types ...any) error {
+	g := &tsGen{seen: map[reflect.Type]bool{}}
+	for _, v := range types {
+		t := reflect.TypeOf(v)
+		if t == nil || t.Kind() != reflect.Struct || t.Name() == "" {
+			return fmt.Errorf("props type %T is not a named struct", v)
+		}
+		g.enqueue(t)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by tilde gen props. DO NOT EDIT.\n")
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		body, err := g.fields(t, "")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\nexport interface %s {\n%s}\n", t.Name(), body)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type tsGen struct {
+	seen  map[reflect.Type]bool
+	queue []reflect.Type
+}
+
+func (g *tsGen) enqueue(t reflect.Type) {
+	if !g.seen[t] {
+		g.seen[t] = true
+		g.queue = append(g.queue, t)
+	}
+}
+
+// fields returns the properties of struct t, one per line, following
+// encoding/json in promoting the fields of untagged embedded structs.
+func (g *tsGen) fields(t reflect.Type, indent string) (string, error) {
+	var b strings.Builder
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s, err := g.fields(ft, indent)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		if !identifier.MatchString(name) {
+			name = fmt.Sprintf("%q", name)
+		}
+		optional := ""
+		if hasOpt(opts, "omitempty") || hasOpt(opts, "omitzero") {
+			optional = "?"
+		}
+
+		typ := "string"
+		if !hasOpt(opts, "string") {
+			var err error
+			if typ, err = g.typeOf(ft, indent+"  "); err != nil {
+				return "", fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, name, optional, typ)
+	}
+	return b.String(), nil
+}
+
+func (g *tsGen) typeOf(t reflect.Type, indent string) (string, error) {
+	// Text marshalers such as time.Time encode as strings, even those that
+	// also implement json.Marshaler; other JSON marshalers could be anything.
+	switch {
+	case t.Implements(textMarshaler):
+		return "string", nil
+	case t.Implements(jsonMarshaler):
+		return "unknown", nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Interface:
+		return "unknown", nil
+	case reflect.Pointer:
+		elem, err := g.typeOf(t.Elem(), indent)
+		return nullable(elem), err
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable("string"), nil
+		}
+		elem, err := g.typeOf(t.Elem(), indent)
+		return nullable(array(elem)), err
+	case reflect.Array:
+		elem, err := g.typeOf(t.Elem(), indent)
+		return array(elem), err
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshaler) {
+				return "", fmt.Errorf("unsupported map key type %s", t.Key())
+			}
+		}
+		elem, err := g.typeOf(t.Elem(), indent)
+		return nullable("Record<string, " + elem + ">"), err
+	case reflect.Struct:
+		if t.Name() != "" {
+			g.enqueue(t)
+			return t.Name(), nil
+		}
+		body, err := g.fields(t, indent)
+		return "{\n" + body + indent + "}", err
+	default:
+		return "", fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func nullable(t string) string {
+	if strings.HasSuffix(t, " | null") {
+		return t
+	}
+	return t + " | null"
+}
+
+func array(elem string) string {
+	if strings.Contains(elem, "|") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
+
+func hasOpt(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
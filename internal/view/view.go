@@ -0,0 +1,208 @@
+// Package view renders HTML pages from html/template files.
+//
+// Templates are read from three directories: layouts/ and partials/ hold
+// templates shared by every page, and each file in pages/ is a page parsed
+// along with them. Rendering a page executes its "base" template, which a
+// layout defines and pages fill in by defining the blocks it calls.
+package view
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jonathonwebb/tilde/internal/bundle"
+)
+
+type Options struct {
+	// Templates holds the layouts, partials and pages directories.
+	Templates fs.FS
+	// Static is the public dir holding the asset manifest.
+	Static fs.FS
+	// Dev re-parses templates whenever they change and links the unhashed
+	// outputs of the dev server's esbuild watcher instead of the manifest.
+	// Otherwise templates and the manifest are read once by New.
+	Dev bool
+	// DevSocketURL is the dev server's websocket, which the devClient helper
+	// points the live reload client at when Dev is set.
+	DevSocketURL string
+	// Funcs are added to the template helpers.
+	Funcs template.FuncMap
+}
+
+type View struct {
+	opts Options
+
+	mu    sync.Mutex
+	pages map[string]*template.Template
+	stamp stamp
+}
+
+// stamp summarizes the files a View was loaded from, to notice changes.
+type stamp struct {
+	files   int
+	modTime time.Time
+}
+
+func New(opts Options) (*View, error) {
+	v := &View{opts: opts}
+	s, err := v.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.load(s); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Render executes page with data into w. A failed render may have written part
+// of the page, so HTTP handlers should render into a buffer.
+func (v *View) Render(w io.Writer, page string, data any) error {
+	t, err := v.page(page)
+	if err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(w, "base", data)
+}
+
+func (v *View) page(name string) (*template.Template, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.opts.Dev {
+		s, err := v.stat()
+		if err != nil {
+			return nil, err
+		}
+		if s != v.stamp {
+			if err := v.load(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	t, ok := v.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("page %q not found", name)
+	}
+	return t, nil
+}
+
+// load parses every page, with v.mu held or before v is shared.
+func (v *View) load(s stamp) error {
+	resolve := resolver(devAsset)
+	if !v.opts.Dev {
+		manifest, err := ReadManifest(v.opts.Static)
+		if err != nil {
+			return err
+		}
+		resolve = manifest.Asset
+	}
+	funcs := resolve.funcs()
+	funcs["devClient"] = v.devClient
+	for name, fn := range v.opts.Funcs {
+		funcs[name] = fn
+	}
+
+	shared, err := glob(v.opts.Templates, "layouts/*.html", "partials/*.html")
+	if err != nil {
+		return err
+	}
+	pages, err := glob(v.opts.Templates, "pages/*.html")
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, p := range pages {
+		name := strings.TrimSuffix(path.Base(p), ".html")
+		t, err := template.New(name).Funcs(funcs).ParseFS(v.opts.Templates, append(shared, p)...)
+		if err != nil {
+			return fmt.Errorf("parse page %q: %w", name, err)
+		}
+		parsed[name] = t
+	}
+
+	v.pages = parsed
+	v.stamp = s
+	return nil
+}
+
+// stat returns the stamp of the templates and the manifest as they are now.
+func (v *View) stat() (stamp, error) {
+	var s stamp
+	add := func(info fs.FileInfo) {
+		s.files++
+		if info.ModTime().After(s.modTime) {
+			s.modTime = info.ModTime()
+		}
+	}
+
+	err := fs.WalkDir(v.opts.Templates, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		add(info)
+		return nil
+	})
+	if err != nil {
+		return stamp{}, err
+	}
+
+	info, err := fs.Stat(v.opts.Static, ManifestPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return stamp{}, err
+	}
+	if err == nil {
+		add(info)
+	}
+	return s, nil
+}
+
+// devClient returns the live reload client and the meta tag telling it where
+// the dev server's websocket is, or nothing outside of development.
+func (v *View) devClient() (template.HTML, error) {
+	if !v.opts.Dev {
+		return "", nil
+	}
+	a, err := devAsset("build/dev-client.ts")
+	if err != nil {
+		return "", err
+	}
+	// Dev builds leave the vendor packages out of each bundle, so that
+	// hot-swapped modules share them with the page.
+	imports := make(map[string]string, len(bundle.Vendor))
+	for pkg, out := range bundle.Vendor {
+		imports[pkg] = "/public/build/" + out + ".js"
+	}
+	importMap, err := json.Marshal(map[string]any{"imports": imports})
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(fmt.Sprintf(`<script type="importmap">%s</script><meta name="dev-socket-url" content="%s">%s`,
+		importMap, template.HTMLEscapeString(v.opts.DevSocketURL), scriptTag(a))), nil
+}
+
+func glob(fsys fs.FS, patterns ...string) ([]string, error) {
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
@@ -0,0 +1,211 @@
+package view_test
+
+import (
+	"encoding/json"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jonathonwebb/tilde/internal/islands"
+	"github.com/jonathonwebb/tilde/internal/view"
+)
+
+var modTime = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func file(data string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(data), ModTime: modTime}
+}
+
+func testFS() (templates, static fstest.MapFS) {
+	templates = fstest.MapFS{
+		"layouts/base.html":  file(`{{define "base"}}<head>{{stylesheet "build/main.css"}}{{devClient}}</head><body>{{template "nav" .}}{{block "main" .}}{{end}}{{script "build/main.tsx"}}</body>{{end}}`),
+		"partials/nav.html":  file(`{{define "nav"}}<nav>{{asset "build/main.tsx"}}</nav>{{end}}`),
+		"pages/home.html":    file(`{{define "main"}}<h1>hello, {{.}}</h1>{{end}}`),
+		"pages/missing.html": file(`{{define "main"}}{{asset "build/nope.tsx"}}{{end}}`),
+	}
+	static = fstest.MapFS{
+		view.ManifestPath: file(`{
+ "build/main.css": {"path": "/public/build/main-AAAAAAAA.css", "sri": "sha384-css"},
+ "build/main.tsx": {"path": "/public/build/main-BBBBBBBB.js", "sri": "sha384-js"}
+}`),
+	}
+	return templates, static
+}
+
+func render(t testing.TB, v *view.View, page string, data any) string {
+	t.Helper()
+	var b strings.Builder
+	if err := v.Render(&b, page, data); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRender(t *testing.T) {
+	templates, static := testFS()
+	v, err := view.New(view.Options{Templates: templates, Static: static})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := render(t, v, "home", "<alice>")
+	want := `<head><link rel="stylesheet" href="/public/build/main-AAAAAAAA.css" integrity="sha384-css" crossorigin="anonymous"></head>` +
+		`<body><nav>/public/build/main-BBBBBBBB.js</nav><h1>hello, &lt;alice&gt;</h1>` +
+		`<script type="module" src="/public/build/main-BBBBBBBB.js" integrity="sha384-js" crossorigin="anonymous"></script></body>`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("render mismatch (-want +got):\n%s", diff)
+	}
+
+	err = v.Render(&strings.Builder{}, "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), `asset "build/nope.tsx" is not in the manifest`) {
+		t.Errorf("want unknown asset error, but got %v", err)
+	}
+	if err := v.Render(&strings.Builder{}, "nope", nil); err == nil {
+		t.Error("want error rendering unknown page, but got nil")
+	}
+}
+
+func TestDev(t *testing.T) {
+	templates, _ := testFS()
+	v, err := view.New(view.Options{Templates: templates, Static: fstest.MapFS{}, Dev: true, DevSocketURL: "ws://localhost:8000/_dev/ws"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := render(t, v, "home", "alice")
+	want := `<head><link rel="stylesheet" href="/public/build/main.css">` +
+		`<script type="importmap">{"imports":{"preact":"/public/build/vendor/preact.js","preact/hooks":"/public/build/vendor/preact-hooks.js"}}</script>` +
+		`<meta name="dev-socket-url" content="ws://localhost:8000/_dev/ws"><script type="module" src="/public/build/dev-client.js"></script></head>` +
+		`<body><nav>/public/build/main.js</nav><h1>hello, alice</h1>` +
+		`<script type="module" src="/public/build/main.js"></script></body>`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("render mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReload(t *testing.T) {
+	for _, dev := range []bool{false, true} {
+		templates, static := testFS()
+		v, err := view.New(view.Options{Templates: templates, Static: static, Dev: dev})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		templates["pages/home.html"] = &fstest.MapFile{Data: []byte(`{{define "main"}}bye{{end}}`), ModTime: modTime.Add(time.Second)}
+		got := render(t, v, "home", nil)
+		if changed := strings.Contains(got, "bye"); changed != dev {
+			t.Errorf("with dev %v, want page changed = %v, but got %q", dev, dev, got)
+		}
+	}
+}
+
+func TestMissingManifest(t *testing.T) {
+	templates, _ := testFS()
+	_, err := view.New(view.Options{Templates: templates, Static: fstest.MapFS{}})
+	if err == nil || !strings.Contains(err.Error(), "run tilde assets") {
+		t.Errorf("want missing manifest error, but got %v", err)
+	}
+}
+
+func TestIsland(t *testing.T) {
+	templates, static := testFS()
+	templates["pages/islands.html"] = file(`{{define "main"}}{{range .}}{{island "build/main.tsx" .}}{{end}}{{end}}`)
+	v, err := view.New(view.Options{Templates: templates, Static: static})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []islands.AppProps{{Name: `"Zoë" & <co>`}, {Name: "bob"}}
+	got := render(t, v, "islands", want)
+
+	script := `<script type="module" src="/public/build/main-BBBBBBBB.js" integrity="sha384-js" crossorigin="anonymous"></script>`
+	mounts := regexp.MustCompile(`<div data-island="main" data-props="([^"]*)"></div>`+regexp.QuoteMeta(script)).FindAllStringSubmatch(got, -1)
+	var props []islands.AppProps
+	for _, m := range mounts {
+		s, err := url.PathUnescape(html.UnescapeString(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p islands.AppProps
+		if err := json.Unmarshal([]byte(s), &p); err != nil {
+			t.Fatal(err)
+		}
+		props = append(props, p)
+	}
+	if diff := cmp.Diff(want, props); diff != "" {
+		t.Errorf("island props mismatch (-want +got):\n%s\nin %s", diff, got)
+	}
+}
+
+type tsUser struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type tsBase struct {
+	Kind string `json:"kind"`
+}
+
+type tsProps struct {
+	tsBase
+	User     tsUser            `json:"user"`
+	Owner    *tsUser           `json:"owner"`
+	Members  []tsUser          `json:"members,omitempty"`
+	Tags     map[string]string `json:"tags"`
+	Scores   [2]*float64       `json:"scores"`
+	Created  time.Time         `json:"created_at"`
+	Count    int               `json:"count,string"`
+	Extra    any               `json:"extra"`
+	Nested   struct{ On bool } `json:"nested"`
+	Dashed   bool              `json:"data-dashed"`
+	Untagged string
+	Skipped  string `json:"-"`
+	private  string
+}
+
+func TestTypeScript(t *testing.T) {
+	var b strings.Builder
+	if err := view.TypeScript(&b, tsProps{}, islands.AppProps{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `// Code generated by tilde gen props. DO NOT EDIT.
+
+export interface tsProps {
+  kind: string;
+  user: tsUser;
+  owner: tsUser | null;
+  members?: tsUser[] | null;
+  tags: Record<string, string> | null;
+  scores: (number | null)[];
+  created_at: string;
+  count: string;
+  extra: unknown;
+  nested: {
+    On: boolean;
+  };
+  "data-dashed": boolean;
+  Untagged: string;
+}
+
+export interface AppProps {
+  name: string;
+}
+
+export interface tsUser {
+  id: number;
+  name: string;
+}
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("typescript mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := view.TypeScript(&b, "not a struct"); err == nil {
+		t.Error("want error for non-struct props, but got nil")
+	}
+}
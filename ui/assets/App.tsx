@@ -1,11 +1,11 @@
 import { h } from "preact";
 import { useState } from "preact/hooks";
+import { hot } from "./hmr";
+import type { AppProps } from "./props.gen";
 
-export interface AppProps {
-    name: string;
-}
+export type { AppProps };
 
-export default function App(props: AppProps) {
+function App(props: AppProps) {
     const [count, setCount] = useState(0);
     return (
         <section>
@@ -17,3 +17,5 @@ export default function App(props: AppProps) {
         </section>
     );
 }
+
+export default hot("App", App);
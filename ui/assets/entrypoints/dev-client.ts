@@ -5,12 +5,43 @@ debug.enable("dev-client");
 
 let reconnecting = false;
 
+// ChangeEvent lists changed outputs by public path. Updated outputs in hot
+// can be re-imported to swap their components into the page in place, and
+// modules lists the source modules whose changes each updated output holds.
 type ChangeEvent = {
+  type: "change";
   added: string[];
   removed: string[];
   updated: string[];
+  modules: Record<string, string[]>;
+  hot: string[];
 };
 
+// BuildMessage mirrors esbuild's errors and warnings: line is 1-based, and
+// column and length are in bytes from the start of the line.
+type BuildMessage = {
+  text: string;
+  file?: string;
+  line: number;
+  column: number;
+  length: number;
+  snippet?: string;
+};
+
+type BuildEvent = {
+  type: "build";
+  errors: BuildMessage[];
+  warnings: BuildMessage[];
+};
+
+// ReloadEvent follows a restart of the app server by tilde dev, or a change
+// to its templates.
+type ReloadEvent = {
+  type: "reload";
+};
+
+type Message = ChangeEvent | BuildEvent | ReloadEvent;
+
 const urlMetaEl = document.head.querySelector<HTMLMetaElement>(
   '[name="dev-socket-url"]'
 );
@@ -47,91 +78,265 @@ function connect() {
     this: WebSocket,
     event: MessageEvent<string>
   ) {
-    const { added, removed, updated } = JSON.parse(event.data) as ChangeEvent;
+    const msg = JSON.parse(event.data) as Message;
+    switch (msg.type) {
+      case "change":
+        handleChange(msg);
+        break;
+      case "build":
+        handleBuild(msg);
+        break;
+      case "reload":
+        log("server asked to reload");
+        location.reload();
+        break;
+      default:
+        log("unknown message %o", msg);
+    }
+  };
 
-    for (const link of document.getElementsByTagName("link")) {
-      if (link.rel !== "stylesheet") continue;
+  socket.onerror = function handleError(this: WebSocket, _event: Event) {
+    // console.error("connection error", event);
+  };
 
-      let url: URL | null = null;
-      try {
-        url = new URL(link.href);
-      } catch (err) {
-        console.error(`invalid stylesheet link href URL: "${link.href}"`);
-        continue;
-      }
+  socket.onclose = function handleClose(this: WebSocket, _event: CloseEvent) {
+    // log("connection closed");
+    reconnecting = true;
+    setTimeout(connect, 2000);
+  };
+}
 
-      if (url.hostname === location.hostname) {
-        for (const path of [...added, ...removed]) {
-          if (path === url.pathname) {
-            log('updated "%s"', path);
-            location.reload();
-            return;
-          }
+function handleChange({ added, removed, updated, modules, hot }: ChangeEvent) {
+  for (const link of document.getElementsByTagName("link")) {
+    if (link.rel !== "stylesheet") continue;
+
+    let url: URL | null = null;
+    try {
+      url = new URL(link.href);
+    } catch (err) {
+      console.error(`invalid stylesheet link href URL: "${link.href}"`);
+      continue;
+    }
+
+    if (url.hostname === location.hostname) {
+      for (const path of [...added, ...removed]) {
+        if (path === url.pathname) {
+          log('updated "%s"', path);
+          location.reload();
+          return;
         }
       }
     }
+  }
 
-    for (const script of document.getElementsByTagName("script")) {
-      if (!script.src) continue;
+  // Several island scripts can share a src, which is swapped once.
+  const swaps = new Set<string>();
+  for (const script of document.getElementsByTagName("script")) {
+    if (!script.src) continue;
 
-      let url: URL | null = null;
-      try {
-        url = new URL(script.src);
-      } catch (err) {
-        console.error(`invalid script src URL: "${script.src}"`);
-        continue;
-      }
+    let url: URL | null = null;
+    try {
+      url = new URL(script.src);
+    } catch (err) {
+      console.error(`invalid script src URL: "${script.src}"`);
+      continue;
+    }
 
-      if (url.hostname === location.hostname) {
-        for (const path of [...added, ...removed, ...updated]) {
-          if (path === url.pathname) {
-            log('updated "%s"', path);
-            location.reload();
-            return;
-          }
+    if (url.hostname === location.hostname) {
+      for (const path of [...added, ...removed, ...updated]) {
+        if (path !== url.pathname) continue;
+        if (updated.includes(path) && hot.includes(path)) {
+          swaps.add(path);
+          continue;
         }
+        log('updated "%s"', path);
+        location.reload();
+        return;
       }
     }
+  }
 
-    const links = Array.from(document.getElementsByTagName("link"));
-    for (const link of links) {
-      if (link.rel !== "stylesheet") continue;
+  for (const path of swaps) {
+    log('hot updating "%s" for %o', path, modules[path] ?? []);
+    hotSwap(path);
+  }
 
-      let url: URL | null = null;
-      try {
-        url = new URL(link.href);
-      } catch (err) {
-        console.error(`invalid stylesheet link href URL: "${link.href}"`);
-      }
-      if (url !== null && url.hostname === location.hostname) {
-        const matching = updated.find((f) => url.pathname === f);
-        if (matching) {
-          log('updated "%s"', matching);
-          const next = link.cloneNode() as HTMLLinkElement;
-          url.searchParams.set("v", Math.random().toString(36).slice(2))
-          next.href = url.href
-          next.onload = () => {
-            link.remove();
-          };
-          if (link.parentNode === null) {
-            console.error("expected stylesheet link to have a parent node");
-          } else {
-            link.parentNode.insertBefore(next, link.nextSibling);
-          }
+  const links = Array.from(document.getElementsByTagName("link"));
+  for (const link of links) {
+    if (link.rel !== "stylesheet") continue;
+
+    let url: URL | null = null;
+    try {
+      url = new URL(link.href);
+    } catch (err) {
+      console.error(`invalid stylesheet link href URL: "${link.href}"`);
+    }
+    if (url !== null && url.hostname === location.hostname) {
+      const matching = updated.find((f) => url.pathname === f);
+      if (matching) {
+        log('updated "%s"', matching);
+        const next = link.cloneNode() as HTMLLinkElement;
+        url.searchParams.set("v", Math.random().toString(36).slice(2))
+        next.href = url.href
+        next.onload = () => {
+          link.remove();
+        };
+        if (link.parentNode === null) {
+          console.error("expected stylesheet link to have a parent node");
+        } else {
+          link.parentNode.insertBefore(next, link.nextSibling);
         }
       }
     }
-  };
+  }
+}
 
-  socket.onerror = function handleError(this: WebSocket, _event: Event) {
-    // console.error("connection error", event);
-  };
+// hotSwap re-imports the script at path, whose hot components swap their new
+// code into the page, or reloads the page if it fails to run.
+async function hotSwap(path: string) {
+  try {
+    await import(`${path}?hmr=${Date.now()}`);
+  } catch (err) {
+    console.error(`hot update of "${path}" failed, reloading`, err);
+    location.reload();
+  }
+}
 
-  socket.onclose = function handleClose(this: WebSocket, _event: CloseEvent) {
-    // log("connection closed");
-    reconnecting = true;
-    setTimeout(connect, 2000);
+function handleBuild({ errors, warnings }: BuildEvent) {
+  for (const w of warnings) {
+    console.warn(`[esbuild] ${formatLocation(w)}${w.text}`);
+  }
+  if (errors.length === 0) {
+    hideOverlay();
+    return;
+  }
+  for (const e of errors) {
+    console.error(`[esbuild] ${formatLocation(e)}${e.text}`);
+  }
+  showOverlay(errors, warnings);
+}
+
+function formatLocation(m: BuildMessage): string {
+  return m.file ? `${m.file}:${m.line}:${m.column}: ` : "";
+}
+
+const overlayStyle = `
+  :host { all: initial; }
+  .backdrop {
+    position: fixed; inset: 0; z-index: 2147483647; overflow: auto;
+    background: rgba(0, 0, 0, 0.66); padding: 2rem;
+    font: 14px/1.5 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace;
+  }
+  .panel {
+    max-width: 960px; margin: 0 auto; padding: 1rem 1.5rem;
+    background: #1e1e1e; color: #e8e8e8; border-top: 4px solid #ff5555;
+    border-radius: 4px; box-shadow: 0 8px 32px rgba(0, 0, 0, 0.5);
+  }
+  header { display: flex; justify-content: space-between; align-items: center; }
+  h1 { font-size: 16px; margin: 0; color: #ff5555; }
+  button {
+    font: inherit; color: inherit; background: none; cursor: pointer;
+    border: 1px solid #555; border-radius: 4px; padding: 0 0.5rem;
+  }
+  .message { margin-top: 1rem; }
+  .message.warning .text { color: #f1c40f; }
+  .text { font-weight: bold; white-space: pre-wrap; }
+  .location { color: #9cdcfe; }
+  pre { margin: 0.25rem 0 0; padding: 0.5rem; background: #111; overflow-x: auto; }
+  .marker { color: #ff5555; }
+  footer { margin-top: 1rem; color: #999; font-size: 12px; }
+`;
+
+let overlay: HTMLElement | null = null;
+
+function hideOverlay() {
+  if (overlay !== null) {
+    overlay.remove();
+    overlay = null;
+  }
+}
+
+function onOverlayKey(event: KeyboardEvent) {
+  if (event.key === "Escape") {
+    hideOverlay();
+    document.removeEventListener("keydown", onOverlayKey);
+  }
+}
+
+// showOverlay replaces any overlay with one listing errors and warnings, kept
+// in a shadow root so that page styles neither affect it nor are affected.
+function showOverlay(errors: BuildMessage[], warnings: BuildMessage[]) {
+  hideOverlay();
+
+  overlay = document.createElement("tilde-dev-overlay");
+  const root = overlay.attachShadow({ mode: "open" });
+  const style = document.createElement("style");
+  style.textContent = overlayStyle;
+  root.appendChild(style);
+
+  const backdrop = el("div", "backdrop");
+  const panel = el("div", "panel");
+  const header = el("header");
+  header.appendChild(
+    el("h1", "", `Build failed with ${plural(errors.length, "error")}`)
+  );
+  const close = el("button", "", "×");
+  close.title = "Dismiss (Esc)";
+  close.onclick = hideOverlay;
+  header.appendChild(close);
+  panel.appendChild(header);
+
+  for (const m of errors) panel.appendChild(renderMessage(m, "error"));
+  for (const m of warnings) panel.appendChild(renderMessage(m, "warning"));
+  panel.appendChild(
+    el("footer", "", "Fix the errors to rebuild, or press Esc to dismiss.")
+  );
+  backdrop.appendChild(panel);
+  backdrop.onclick = (event) => {
+    if (event.target === backdrop) hideOverlay();
   };
+  root.appendChild(backdrop);
+
+  document.addEventListener("keydown", onOverlayKey);
+  document.body.appendChild(overlay);
+}
+
+function renderMessage(m: BuildMessage, kind: "error" | "warning"): HTMLElement {
+  const div = el("div", `message ${kind}`);
+  div.appendChild(el("div", "text", `${kind}: ${m.text}`));
+  if (m.file) {
+    div.appendChild(el("div", "location", `${m.file}:${m.line}:${m.column}`));
+  }
+  if (m.snippet !== undefined) {
+    // Columns count bytes, so the marker lines up only for ASCII lines, which
+    // covers the usual case of code.
+    const pre = el("pre");
+    pre.appendChild(document.createTextNode(`${m.snippet}\n`));
+    pre.appendChild(
+      el(
+        "span",
+        "marker",
+        " ".repeat(m.column) + "^".repeat(Math.max(m.length, 1))
+      )
+    );
+    div.appendChild(pre);
+  }
+  return div;
+}
+
+function el<K extends keyof HTMLElementTagNameMap>(
+  tag: K,
+  className = "",
+  text = ""
+): HTMLElementTagNameMap[K] {
+  const e = document.createElement(tag);
+  if (className) e.className = className;
+  if (text) e.textContent = text;
+  return e;
+}
+
+function plural(n: number, word: string): string {
+  return `${n} ${word}${n === 1 ? "" : "s"}`;
 }
 
 connect();
@@ -1,11 +1,14 @@
 import { h, render } from "preact";
 import App, {type AppProps} from "../App"
 
-const root = document.getElementById("app");
-if (!root) {
+// Islands rendered by the server's island helper carry their props as
+// URL-encoded JSON, and there may be several on a page.
+const roots = document.querySelectorAll<HTMLElement>('[data-island="main"]');
+if (roots.length === 0) {
     throw new Error("app root not found");
 }
 
-const props: AppProps = JSON.parse(decodeURIComponent(root.getAttribute("data-props")!));
-
-render(<App name={props.name} />, document.getElementById("app")!)
+for (const root of roots) {
+    const props: AppProps = JSON.parse(decodeURIComponent(root.dataset.props!));
+    render(<App {...props} />, root);
+}
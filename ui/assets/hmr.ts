@@ -0,0 +1,70 @@
+import { type FunctionComponent } from "preact";
+import { useEffect, useReducer } from "preact/hooks";
+
+// __TILDE_HMR__ is true in dev server builds only.
+declare const __TILDE_HMR__: boolean;
+
+type Entry = {
+  impl: FunctionComponent<any>;
+  signature: string;
+  proxy: FunctionComponent<any>;
+  instances: Set<() => void>;
+};
+
+// The registry outlives the modules that fill it, as re-importing a bundle
+// runs a fresh copy of this one.
+const registry: Map<string, Entry> = __TILDE_HMR__
+  ? ((globalThis as any).__tildeHMR ??= new Map())
+  : new Map();
+
+// hooks lists the hooks a component calls, in order. Hook state can only be
+// kept across a swap if they stay the same.
+function hooks(impl: FunctionComponent<any>): string {
+  return (impl.toString().match(/\buse[A-Z]\w*/g) ?? []).join(",");
+}
+
+// hot returns a component rendering impl that, in dev, keeps its state when a
+// re-imported module registers a new impl under the same id. Modules export
+// the result in place of their component:
+//
+//   export default hot("App", App);
+//
+// A new impl calling different hooks can't keep their state, so the page is
+// reloaded instead. Outside dev, hot returns impl.
+export function hot<P>(id: string, impl: FunctionComponent<P>): FunctionComponent<P> {
+  if (!__TILDE_HMR__) {
+    return impl;
+  }
+
+  const prev = registry.get(id);
+  if (prev !== undefined) {
+    if (hooks(impl) !== prev.signature) {
+      location.reload();
+    } else {
+      prev.impl = impl;
+      prev.instances.forEach((update) => update());
+    }
+    return prev.proxy;
+  }
+
+  const entry: Entry = {
+    impl,
+    signature: hooks(impl),
+    proxy: function Hot(props: P) {
+      const [, update] = useReducer((n: number, _: void) => n + 1, 0);
+      useEffect(() => {
+        entry.instances.add(update);
+        return () => {
+          entry.instances.delete(update);
+        };
+      }, []);
+      // Calling impl rather than rendering it keeps its hooks on this
+      // component, which outlives each impl.
+      return entry.impl(props);
+    },
+    instances: new Set(),
+  };
+  entry.proxy.displayName = impl.displayName ?? impl.name;
+  registry.set(id, entry);
+  return entry.proxy;
+}